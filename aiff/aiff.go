@@ -0,0 +1,249 @@
+// package aiff reads AIFF files.
+package aiff
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/pfcm/audiofile/internal/frameio"
+	"github.com/pfcm/audiofile/riff"
+)
+
+// Reader reads the audio from an AIFF file. It has the same Read methods as
+// wav.Reader, so code can handle both formats.
+type Reader struct {
+	channels   int
+	bitDepth   int
+	sampleRate float64
+	frames     int64
+
+	// data reads the samples, and returns io.EOF at the end of them.
+	data    io.Reader
+	scratch []byte
+}
+
+// NewReader reads the header of an AIFF file, up to the start of the audio in
+// the SSND chunk. The COMM chunk has to come before the SSND chunk, which is
+// almost always the case. Only uncompressed AIFF is supported, not AIFF-C.
+func NewReader(r io.Reader) (*Reader, error) {
+	rr, err := riff.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	if rr.Form != "AIFF" {
+		return nil, fmt.Errorf("expected form AIFF, got %q", rr.Form)
+	}
+	comm, err := rr.ReadUntil("COMM")
+	if err != nil {
+		return nil, err
+	}
+	ar, err := readComm(comm)
+	if err != nil {
+		return nil, err
+	}
+	ssnd, err := rr.ReadUntil("SSND")
+	if err != nil {
+		return nil, err
+	}
+	// The samples start after offset bytes, which follow the offset and
+	// block size.
+	var hdr [8]byte
+	if _, err := io.ReadFull(ssnd, hdr[:]); err != nil {
+		return nil, fmt.Errorf("reading SSND chunk: %w", err)
+	}
+	offset := binary.BigEndian.Uint32(hdr[:])
+	if _, err := io.CopyN(io.Discard, ssnd, int64(offset)); err != nil {
+		return nil, fmt.Errorf("skipping %d bytes of SSND chunk: %w", offset, err)
+	}
+	ar.data = io.LimitReader(ssnd, ar.frames*int64(ar.frameSize()))
+	return ar, nil
+}
+
+// commSize is the size of the COMM chunk in an uncompressed AIFF file.
+const commSize = 18
+
+// readComm parses the COMM chunk, which describes the format of the audio.
+func readComm(c *riff.Chunk) (*Reader, error) {
+	if c.Size < commSize {
+		return nil, fmt.Errorf("COMM chunk too short: %d bytes, need %d", c.Size, commSize)
+	}
+	var raw [commSize]byte
+	if _, err := io.ReadFull(c, raw[:]); err != nil {
+		return nil, fmt.Errorf("reading COMM chunk: %w", err)
+	}
+	ar := &Reader{
+		channels:   int(binary.BigEndian.Uint16(raw[0:])),
+		frames:     int64(binary.BigEndian.Uint32(raw[2:])),
+		bitDepth:   int(binary.BigEndian.Uint16(raw[6:])),
+		sampleRate: fromExtended(raw[8:]),
+	}
+	if ar.channels == 0 {
+		return nil, errors.New("COMM chunk has 0 channels")
+	}
+	if ar.bitDepth < 1 || ar.bitDepth > 32 {
+		return nil, fmt.Errorf("unsupported bit depth %d", ar.bitDepth)
+	}
+	// The exponent can also encode infinity and NaN.
+	if math.IsInf(ar.sampleRate, 0) || math.IsNaN(ar.sampleRate) || ar.sampleRate <= 0 {
+		return nil, fmt.Errorf("invalid sample rate %v", ar.sampleRate)
+	}
+	return ar, nil
+}
+
+// fromExtended decodes the 80 bit IEEE-754 extended precision float in the
+// first 10 bytes of raw, which is how AIFF stores the sample rate. It has a
+// sign bit, a 15 bit exponent and a 64 bit mantissa with an explicit integer
+// bit.
+func fromExtended(raw []byte) float64 {
+	se := binary.BigEndian.Uint16(raw)
+	mantissa := binary.BigEndian.Uint64(raw[2:])
+	exp := int(se&0x7FFF) - 16383 - 63
+	f := math.Ldexp(float64(mantissa), exp)
+	if se&0x8000 != 0 {
+		f = -f
+	}
+	return f
+}
+
+// Channels returns the number of channels in the file.
+func (r *Reader) Channels() int {
+	return r.channels
+}
+
+// BitDepth returns the number of bits per sample in the file.
+func (r *Reader) BitDepth() int {
+	return r.bitDepth
+}
+
+// Samplerate returns the sample rate of the file. AIFF can store any sample
+// rate, but it is almost always a whole number, so it is rounded.
+func (r *Reader) Samplerate() int {
+	return int(math.Round(r.sampleRate))
+}
+
+// Samples returns the number of samples per channel in the file.
+func (r *Reader) Samples() int {
+	return int(min(r.frames, math.MaxInt))
+}
+
+// frameSize is the number of bytes in a frame. Samples that aren't a whole
+// number of bytes are padded out to the next byte.
+func (r *Reader) frameSize() int {
+	return (r.bitDepth + 7) / 8 * r.channels
+}
+
+// Read16PCM fills the provided slices with 16 bit PCM data from the file,
+// returning the number of samples read per channel. Samples with more bits have
+// the extra ones truncated.
+func (r *Reader) Read16PCM(data [][]int16) (int, error) {
+	next, err := r.intDecoder()
+	if err != nil {
+		return 0, err
+	}
+	return readInto(data, r, func(b []byte) (int16, []byte) {
+		i, b := next(b)
+		return int16(i >> 16), b
+	})
+}
+
+// Read32Float reads some of the data into 32 bit floats, between -1 and 1. The
+// samples are scaled the same way as wav.Reader's, so the same audio gives the
+// same floats from either.
+func (r *Reader) Read32Float(data [][]float32) (int, error) {
+	next, err := r.intDecoder()
+	if err != nil {
+		return 0, err
+	}
+	bits := (r.bitDepth + 7) / 8 * 8
+	shift := 32 - bits
+	div := 1 / float32(int64(1)<<(bits-1)-1)
+	if bits == 8 {
+		div = 1.0 / 128
+	}
+	return readInto(data, r, func(b []byte) (float32, []byte) {
+		i, b := next(b)
+		return float32(i>>shift) * div, b
+	})
+}
+
+// intDecoder returns a function that decodes a single sample into an int32,
+// scaled up to the full 32 bits, returning the remaining bytes. AIFF samples
+// are big-endian two's complement, including 8 bit ones, and already aligned to
+// the most significant bit.
+func (r *Reader) intDecoder() (func([]byte) (int32, []byte), error) {
+	switch size := (r.bitDepth + 7) / 8; size {
+	case 1:
+		return func(b []byte) (int32, []byte) {
+			return int32(b[0]) << 24, b[1:]
+		}, nil
+	case 2:
+		return func(b []byte) (int32, []byte) {
+			return int32(binary.BigEndian.Uint16(b)) << 16, b[2:]
+		}, nil
+	case 3:
+		return func(b []byte) (int32, []byte) {
+			return int32(b[0])<<24 | int32(b[1])<<16 | int32(b[2])<<8, b[3:]
+		}, nil
+	case 4:
+		return func(b []byte) (int32, []byte) {
+			return int32(binary.BigEndian.Uint32(b)), b[4:]
+		}, nil
+	default:
+		return nil, fmt.Errorf("bit depth %d not implemented", r.bitDepth)
+	}
+}
+
+// readInto reads enough audio to fill data, decoding each sample with next, and
+// returns the number of frames read. If the SSND chunk ends part way through a
+// frame, the whole frames before it are returned along with
+// io.ErrUnexpectedEOF.
+func readInto[T any](data [][]T, r *Reader, next func([]byte) (T, []byte)) (int, error) {
+	return frameio.ReadInto(data, r.channels, r.frameSize(), r.readN, next)
+}
+
+// readN reads up to n bytes of audio into the scratch buffer and returns them.
+// It only returns fewer at the end of the audio.
+func (r *Reader) readN(n int) ([]byte, error) {
+	if cap(r.scratch) < n {
+		r.scratch = make([]byte, n)
+	}
+	scratch := r.scratch[:n]
+	gotN, err := io.ReadFull(r.data, scratch)
+	if gotN > 0 && errors.Is(err, io.ErrUnexpectedEOF) {
+		err = nil // we'll return a normal EOF later
+	}
+	return scratch[:gotN], err
+}
+
+// ReadFull16PCM reads all the audio data, deinterleaving and converting to 16
+// bit PCM if necessary.
+func ReadFull16PCM(r *Reader) ([][]int16, error) {
+	n, err := r.fullSamples()
+	if err != nil {
+		return nil, err
+	}
+	return frameio.ReadAll(r.Read16PCM, r.Channels(), n)
+}
+
+// ReadFull32Float reads all the audio data, deinterleaving and converting to 32
+// bit floats.
+func ReadFull32Float(r *Reader) ([][]float32, error) {
+	n, err := r.fullSamples()
+	if err != nil {
+		return nil, err
+	}
+	return frameio.ReadAll(r.Read32Float, r.Channels(), n)
+}
+
+// fullSamples returns the number of samples per channel for the ReadFull
+// functions to read, or an error if there are too many to hold in memory on
+// this platform.
+func (r *Reader) fullSamples() (int, error) {
+	if r.frames > int64(math.MaxInt/r.frameSize()) {
+		return 0, fmt.Errorf("%d frames of audio are too many to read at once", r.frames)
+	}
+	return int(r.frames), nil
+}
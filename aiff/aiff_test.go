@@ -0,0 +1,267 @@
+package aiff
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pfcm/audiofile/wav"
+)
+
+// The AIFF fixture holds the same audio as kick.wav.
+func openBoth(t *testing.T) (*Reader, *wav.Reader) {
+	t.Helper()
+	raw, err := os.ReadFile("../testdata/kick.aiff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ar, err := NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err = os.ReadFile("../testdata/kick.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wr, err := wav.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ar, wr
+}
+
+func TestMatchesWav(t *testing.T) {
+	ar, wr := openBoth(t)
+	type format struct {
+		Channels, BitDepth, Samplerate, Samples int
+	}
+	got := format{ar.Channels(), ar.BitDepth(), ar.Samplerate(), ar.Samples()}
+	want := format{wr.Channels(), wr.BitDepth(), wr.Samplerate(), wr.Samples()}
+	if d := cmp.Diff(got, want); d != "" {
+		t.Errorf("format mismatch (-got, +want):\n%v", d)
+	}
+
+	got16, err := ReadFull16PCM(ar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want16, err := wav.ReadFull16PCM(wr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff(got16, want16); d != "" {
+		t.Errorf("Read16PCM mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestMatchesWavFloat(t *testing.T) {
+	ar, wr := openBoth(t)
+	got, err := ReadFull32Float(ar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := wav.ReadFull32Float(wr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff(got, want); d != "" {
+		t.Errorf("Read32Float mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestFromExtended(t *testing.T) {
+	for _, c := range []struct {
+		raw  []byte
+		want float64
+	}{
+		{[]byte{0x40, 0x0E, 0xAC, 0x44, 0, 0, 0, 0, 0, 0}, 44100},
+		{[]byte{0x40, 0x0E, 0xBB, 0x80, 0, 0, 0, 0, 0, 0}, 48000},
+		{[]byte{0x40, 0x0B, 0xFA, 0, 0, 0, 0, 0, 0, 0}, 8000},
+		{[]byte{0x3F, 0xFF, 0x80, 0, 0, 0, 0, 0, 0, 0}, 1},
+		{[]byte{0xBF, 0xFE, 0x80, 0, 0, 0, 0, 0, 0, 0}, -0.5},
+		{make([]byte, 10), 0},
+	} {
+		if got := fromExtended(c.raw); got != c.want {
+			t.Errorf("fromExtended(%x) = %v, want %v", c.raw, got, c.want)
+		}
+	}
+}
+
+func TestNotAIFF(t *testing.T) {
+	raw, err := os.ReadFile("../testdata/kick.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewReader(bytes.NewReader(raw)); err == nil {
+		t.Error("NewReader with a wav file: expected error")
+	}
+}
+
+// rate44100 is 44100 as an 80 bit extended float.
+var rate44100 = []byte{0x40, 0x0E, 0xAC, 0x44, 0, 0, 0, 0, 0, 0}
+
+// chunk builds a chunk with a big-endian size, padded to an even length.
+func chunk(id string, body []byte) []byte {
+	out := append([]byte(id), binary.BigEndian.AppendUint32(nil, uint32(len(body)))...)
+	out = append(out, body...)
+	if len(body)%2 == 1 {
+		out = append(out, 0)
+	}
+	return out
+}
+
+// makeAIFF builds an AIFF file with a COMM chunk describing the format and an
+// SSND chunk holding offset bytes of padding followed by data.
+func makeAIFF(channels, frames, bitDepth int, rate []byte, offset int, data []byte) []byte {
+	var comm []byte
+	comm = binary.BigEndian.AppendUint16(comm, uint16(channels))
+	comm = binary.BigEndian.AppendUint32(comm, uint32(frames))
+	comm = binary.BigEndian.AppendUint16(comm, uint16(bitDepth))
+	comm = append(comm, rate...)
+
+	var ssnd []byte
+	ssnd = binary.BigEndian.AppendUint32(ssnd, uint32(offset))
+	ssnd = binary.BigEndian.AppendUint32(ssnd, 0)
+	ssnd = append(ssnd, bytes.Repeat([]byte{0xAA}, offset)...)
+	ssnd = append(ssnd, data...)
+
+	body := append([]byte("AIFF"), chunk("COMM", comm)...)
+	body = append(body, chunk("SSND", ssnd)...)
+	return chunk("FORM", body)
+}
+
+func TestSynthetic(t *testing.T) {
+	for _, c := range []struct {
+		name                       string
+		channels, bitDepth, offset int
+		data                       []byte
+		want                       [][]int16
+	}{{
+		name:     "8 bit",
+		channels: 1,
+		bitDepth: 8,
+		data:     []byte{0x7F, 0x80, 0x00, 0xFF},
+		want:     [][]int16{{0x7F00, -0x8000, 0, -0x100}},
+	}, {
+		name:     "16 bit stereo",
+		channels: 2,
+		bitDepth: 16,
+		data:     []byte{0x12, 0x34, 0xFF, 0xFE, 0x80, 0x00, 0x7F, 0xFF},
+		want:     [][]int16{{0x1234, -0x8000}, {-2, 0x7FFF}},
+	}, {
+		name:     "24 bit",
+		channels: 1,
+		bitDepth: 24,
+		data:     []byte{0x12, 0x34, 0x56, 0x80, 0x00, 0x01},
+		want:     [][]int16{{0x1234, -0x8000}},
+	}, {
+		name:     "32 bit",
+		channels: 1,
+		bitDepth: 32,
+		data:     []byte{0x12, 0x34, 0x56, 0x78, 0xFF, 0xFF, 0xFF, 0xFF},
+		want:     [][]int16{{0x1234, -1}},
+	}, {
+		name:     "20 bit stereo",
+		channels: 2,
+		bitDepth: 20,
+		data:     []byte{0x12, 0x34, 0x50, 0xFF, 0xFF, 0xF0},
+		want:     [][]int16{{0x1234}, {-1}},
+	}, {
+		name:     "offset",
+		channels: 1,
+		bitDepth: 16,
+		offset:   3,
+		data:     []byte{0x00, 0x01, 0x00, 0x02},
+		want:     [][]int16{{1, 2}},
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			frames := len(c.want[0])
+			raw := makeAIFF(c.channels, frames, c.bitDepth, rate44100, c.offset, c.data)
+			r, err := NewReader(bytes.NewReader(raw))
+			if err != nil {
+				t.Fatal(err)
+			}
+			type format struct {
+				Channels, BitDepth, Samplerate, Samples int
+			}
+			gotFormat := format{r.Channels(), r.BitDepth(), r.Samplerate(), r.Samples()}
+			wantFormat := format{c.channels, c.bitDepth, 44100, frames}
+			if d := cmp.Diff(gotFormat, wantFormat); d != "" {
+				t.Errorf("format mismatch (-got, +want):\n%v", d)
+			}
+			got, err := ReadFull16PCM(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d := cmp.Diff(got, c.want); d != "" {
+				t.Errorf("Read16PCM mismatch (-got, +want):\n%v", d)
+			}
+		})
+	}
+}
+
+func TestTruncatedSSND(t *testing.T) {
+	// Two 16 bit stereo frames, cut off half way through the second.
+	data := []byte{0x00, 0x01, 0x00, 0x02, 0x00, 0x03, 0x00, 0x04}
+	raw := makeAIFF(2, 2, 16, rate44100, 0, data)
+	raw = raw[:len(raw)-2]
+	r, err := NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := [][]int16{make([]int16, 2), make([]int16, 2)}
+	n, err := r.Read16PCM(got)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Read16PCM on a truncated SSND chunk: got error %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+	if n != 1 {
+		t.Fatalf("Read16PCM on a truncated SSND chunk read %d frames, want 1", n)
+	}
+	if d := cmp.Diff([][]int16{got[0][:n], got[1][:n]}, [][]int16{{1}, {2}}); d != "" {
+		t.Errorf("Read16PCM mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestShortSSND(t *testing.T) {
+	// COMM declares three frames, but SSND only holds two.
+	raw := makeAIFF(1, 3, 16, rate44100, 0, []byte{0x00, 0x01, 0x00, 0x02})
+	r, err := NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadFull16PCM(r); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("ReadFull16PCM on a short SSND chunk: got error %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestHugeFrameCount(t *testing.T) {
+	raw := makeAIFF(1, 1, 16, rate44100, 0, []byte{0x00, 0x01})
+	// The frame count follows the FORM and COMM headers and the channels.
+	binary.BigEndian.PutUint32(raw[22:], math.MaxUint32)
+	r, err := NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := r.Samples(), int(min(math.MaxUint32, math.MaxInt)); got != want {
+		t.Errorf("Samples() = %d, want %d", got, want)
+	}
+}
+
+func TestBadSampleRate(t *testing.T) {
+	for _, rate := range [][]byte{
+		make([]byte, 10),
+		{0x7F, 0xFF, 0x80, 0, 0, 0, 0, 0, 0, 0},
+		{0xFF, 0xFF, 0x80, 0, 0, 0, 0, 0, 0, 0},
+		{0xC0, 0x0E, 0xAC, 0x44, 0, 0, 0, 0, 0, 0},
+	} {
+		raw := makeAIFF(1, 1, 16, rate, 0, []byte{0, 0})
+		if _, err := NewReader(bytes.NewReader(raw)); err == nil {
+			t.Errorf("NewReader with sample rate %x: expected error", rate)
+		}
+	}
+}
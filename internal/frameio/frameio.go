@@ -0,0 +1,101 @@
+// Package frameio has the helpers for reading interleaved audio that the wav and
+// aiff readers share.
+package frameio
+
+import (
+	"fmt"
+	"io"
+)
+
+// blockFrames is how many frames ReadUntilEOF asks for at a time.
+const blockFrames = 4096
+
+// ReadInto reads enough audio to fill data, decoding each sample with next, and
+// returns the number of frames read. readN should return up to n bytes,
+// returning fewer only at the end of the audio, and io.EOF if there are none
+// left. If the audio ends part way through a frame, the whole frames before it
+// are returned along with io.ErrUnexpectedEOF.
+func ReadInto[T any](data [][]T, channels, frameSize int, readN func(n int) ([]byte, error), next func([]byte) (T, []byte)) (int, error) {
+	if len(data) != channels {
+		return 0, fmt.Errorf("wrong number of channels: got: %d, file has: %d", len(data), channels)
+	}
+	for c := range data {
+		if len(data[c]) != len(data[0]) {
+			return 0, fmt.Errorf("channel %d has room for %d samples, channel 0 has %d", c, len(data[c]), len(data[0]))
+		}
+	}
+	nBytes := len(data[0]) * frameSize
+	raw, err := readN(nBytes)
+	if err != nil {
+		return 0, err
+	}
+	// If the file was cut off part way through a frame, decode all of the
+	// whole frames before reporting it.
+	if partial := len(raw) % frameSize; partial != 0 {
+		raw = raw[:len(raw)-partial]
+		err = io.ErrUnexpectedEOF
+	}
+	// decode and de-interleave
+	readSamples := 0
+	for j := 0; j < len(data[0]) && len(raw) > 0; j++ {
+		for c := range data {
+			data[c][j], raw = next(raw)
+		}
+		readSamples++
+	}
+	if len(raw) != 0 {
+		return 0, fmt.Errorf("internal error: could not use all the bytes: %d/%d left", len(raw), nBytes)
+	}
+	return readSamples, err
+}
+
+// ReadAll reads samples frames with read, returning io.ErrUnexpectedEOF if the
+// audio runs out before then. If samples is negative the length isn't known up
+// front, and it reads until io.EOF instead.
+func ReadAll[T any](read func([][]T) (int, error), channels, samples int) ([][]T, error) {
+	if samples < 0 {
+		return ReadUntilEOF(read, channels)
+	}
+	data := makeSlices[T](channels, samples)
+	n, err := read(data)
+	if err == io.EOF || (err == nil && n != samples) {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read %d of %d samples: %w", n, samples, err)
+	}
+	return data, nil
+}
+
+// ReadUntilEOF reads blocks of audio with read until it runs out, for when the
+// total length isn't known up front.
+func ReadUntilEOF[T any](read func([][]T) (int, error), channels int) ([][]T, error) {
+	var (
+		data = make([][]T, channels)
+		buf  = makeSlices[T](channels, blockFrames)
+	)
+	for {
+		n, err := read(buf)
+		for c := range data {
+			data[c] = append(data[c], buf[c][:n]...)
+		}
+		if err == io.EOF {
+			return data, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// makeSlices makes a slice of slices of a provided shape that shares a single
+// contiguous backing array. Each slice is capped at its own length, so
+// appending to one doesn't overwrite the next.
+func makeSlices[T any](iSize, jSize int) [][]T {
+	backing := make([]T, iSize*jSize)
+	out := make([][]T, iSize)
+	for i := range out {
+		out[i] = backing[i*jSize : (i+1)*jSize : (i+1)*jSize]
+	}
+	return out
+}
@@ -0,0 +1,74 @@
+package frameio
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// bytesReader returns a readN function that reads from raw, the way the wav and
+// aiff readers do.
+func bytesReader(raw []byte) func(int) ([]byte, error) {
+	return func(n int) ([]byte, error) {
+		if len(raw) == 0 {
+			return nil, io.EOF
+		}
+		n = min(n, len(raw))
+		out := raw[:n]
+		raw = raw[n:]
+		return out, nil
+	}
+}
+
+func nextByte(b []byte) (byte, []byte) { return b[0], b[1:] }
+
+func TestReadInto(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		raw     []byte
+		want    [][]byte
+		wantErr error
+	}{{
+		name: "whole frames",
+		raw:  []byte{1, 2, 3, 4},
+		want: [][]byte{{1, 3}, {2, 4}},
+	}, {
+		name:    "partial frame",
+		raw:     []byte{1, 2, 3},
+		want:    [][]byte{{1}, {2}},
+		wantErr: io.ErrUnexpectedEOF,
+	}, {
+		name:    "empty",
+		want:    [][]byte{{}, {}},
+		wantErr: io.EOF,
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			data := makeSlices[byte](2, 2)
+			n, err := ReadInto(data, 2, 2, bytesReader(c.raw), nextByte)
+			if !errors.Is(err, c.wantErr) {
+				t.Errorf("ReadInto: got error %v, want %v", err, c.wantErr)
+			}
+			if d := cmp.Diff([][]byte{data[0][:n], data[1][:n]}, c.want); d != "" {
+				t.Errorf("ReadInto mismatch (-got, +want):\n%v", d)
+			}
+		})
+	}
+}
+
+func TestReadAll(t *testing.T) {
+	read := func(data [][]byte) (int, error) {
+		return ReadInto(data, 1, 1, bytesReader([]byte{1, 2}), nextByte)
+	}
+	got, err := ReadAll(read, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff(got, [][]byte{{1, 2}}); d != "" {
+		t.Errorf("ReadAll mismatch (-got, +want):\n%v", d)
+	}
+	if _, err := ReadAll(read, 1, 3); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("ReadAll past the end: got error %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
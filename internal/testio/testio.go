@@ -0,0 +1,29 @@
+// Package testio has io helpers for the tests of the other packages.
+package testio
+
+import "io"
+
+// DiscardSeeker is an io.WriteSeeker that throws away everything written to it,
+// while keeping track of the position and size, so tests can write huge files
+// without holding them in memory.
+type DiscardSeeker struct {
+	pos, size int64
+}
+
+func (d *DiscardSeeker) Write(p []byte) (int, error) {
+	d.pos += int64(len(p))
+	d.size = max(d.size, d.pos)
+	return len(p), nil
+}
+
+func (d *DiscardSeeker) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+		d.pos = offset
+	case io.SeekCurrent:
+		d.pos += offset
+	case io.SeekEnd:
+		d.pos = d.size + offset
+	}
+	return d.pos, nil
+}
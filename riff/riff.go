@@ -6,14 +6,16 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 )
 
 // Chunk is a RIFF chunk.
 type Chunk struct {
 	// Identifier is the 4 byte ASCII identifier for the chunk.
 	Identifier string
-	// Size is the number of bytes in the chunk.
-	Size int
+	// Size is the number of bytes in the chunk. It is an int64 because
+	// chunks in RF64 files can be bigger than 4GB.
+	Size int64
 	// Reader is a reader which will read the whole chunk. It
 	// will return io.EOF after Size bytes.
 	io.Reader
@@ -25,23 +27,49 @@ type Chunk struct {
 type Reader struct {
 	// Form is the type of the RIFF file.
 	Form string
+	// ByteOrder is the byte order of the sizes in the file, and usually
+	// the data as well. It is little-endian for RIFF files and big-endian
+	// for RIFX and IFF files.
+	ByteOrder binary.ByteOrder
 
-	r       io.Reader
-	hdr     chunkHeader
-	chunk   Chunk
-	pad     bool
+	r     io.Reader
+	hdr   chunkHeader
+	chunk Chunk
+	pad   bool
+	// sizes holds the real sizes of chunks too big for their 32 bit size
+	// field, from the ds64 chunk of an RF64 file.
+	sizes   map[[4]byte]uint64
 	scratch [4096]byte
 }
 
 // NewReader validates the RIFF header and returns a Reader ready to read
 // chunks. It performs many small reads, a buffered reader is advised.
+//
+// RF64 and BW64 files, which are RIFF files with a ds64 chunk holding 64 bit
+// sizes, are also supported. The ds64 chunk is consumed by NewReader, and
+// chunks whose 32 bit size is 0xFFFFFFFF get their real size from it.
+//
+// So are IFF files, which RIFF is based on and which are laid out the same way
+// as RIFX files, starting with FORM instead. AIFF files are IFF files.
 func NewReader(r io.Reader) (*Reader, error) {
 	var rh chunkHeader
-	if err := readChunkHeader(r, &rh); err != nil {
+	if err := readChunkHeader(r, binary.LittleEndian, &rh); err != nil {
 		return nil, err
 	}
-	if rh.id != [4]byte{'R', 'I', 'F', 'F'} {
-		return nil, fmt.Errorf("expected ID RIFF in first chunk, found: %q", rh.id)
+	var (
+		order binary.ByteOrder
+		rf64  bool
+	)
+	switch rh.id {
+	case [4]byte{'R', 'I', 'F', 'F'}:
+		order = binary.LittleEndian
+	case [4]byte{'R', 'I', 'F', 'X'}, [4]byte{'F', 'O', 'R', 'M'}:
+		order = binary.BigEndian
+	case [4]byte{'R', 'F', '6', '4'}, [4]byte{'B', 'W', '6', '4'}:
+		order = binary.LittleEndian
+		rf64 = true
+	default:
+		return nil, fmt.Errorf("expected ID RIFF, RIFX or FORM in first chunk, found: %q", rh.id)
 	}
 	// Next 4 bytes should be the form type.
 	var f [4]byte
@@ -54,7 +82,69 @@ func NewReader(r io.Reader) (*Reader, error) {
 
 	// The overall size doesn't actually matter, we expect to just read
 	// until EOF anyway.
-	return &Reader{Form: string(f[:]), r: r, pad: rh.pad}, nil
+	rr := &Reader{Form: string(f[:]), ByteOrder: order, r: r, pad: rh.pad}
+	if rf64 {
+		sizes, err := readDS64(r)
+		if err != nil {
+			return nil, err
+		}
+		rr.sizes = sizes
+	}
+	return rr, nil
+}
+
+// maxDS64Size is the biggest ds64 chunk readDS64 accepts: the fixed fields and
+// a table of up to 64 entries.
+const maxDS64Size = 8 + 8 + 8 + 4 + 64*12
+
+// readDS64 reads the ds64 chunk that has to come first in an RF64 file, and
+// returns the 64 bit sizes it holds for the data chunk and anything in its
+// table.
+func readDS64(r io.Reader) (map[[4]byte]uint64, error) {
+	var hdr chunkHeader
+	if err := readChunkHeader(r, binary.LittleEndian, &hdr); err != nil {
+		if err == io.EOF {
+			err = errors.New("unexpected EOF, expecting ds64 chunk")
+		}
+		return nil, err
+	}
+	if hdr.id != [4]byte{'d', 's', '6', '4'} {
+		return nil, fmt.Errorf("expected ds64 chunk first in RF64 file, found: %q", hdr.id)
+	}
+	// The RIFF size, data size and sample count, then the number of table
+	// entries.
+	const fixedSize = 8 + 8 + 8 + 4
+	if hdr.size < fixedSize {
+		return nil, fmt.Errorf("ds64 chunk too small: %d bytes", hdr.size)
+	}
+	// The table is usually empty, so anything much bigger than that is
+	// probably corrupt, and shouldn't make us allocate lots of memory.
+	if hdr.size > maxDS64Size {
+		return nil, fmt.Errorf("ds64 chunk too big: %d bytes, at most %d allowed", hdr.size, maxDS64Size)
+	}
+	body := make([]byte, hdr.size)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, fmt.Errorf("reading ds64 chunk: %w", err)
+	}
+	if hdr.pad {
+		if _, err := io.ReadFull(r, make([]byte, 1)); err != nil {
+			return nil, err
+		}
+	}
+	sizes := map[[4]byte]uint64{
+		{'d', 'a', 't', 'a'}: binary.LittleEndian.Uint64(body[8:]),
+	}
+	table := body[fixedSize:]
+	n := binary.LittleEndian.Uint32(body[24:])
+	// Each entry is a chunk ID and a 64 bit size.
+	if uint64(n)*12 > uint64(len(table)) {
+		return nil, fmt.Errorf("ds64 chunk: table of %d entries in %d bytes", n, len(table))
+	}
+	for i := range int(n) {
+		e := table[i*12:]
+		sizes[[4]byte(e[:4])] = binary.LittleEndian.Uint64(e[4:])
+	}
+	return sizes, nil
 }
 
 // ReadChunk reads the next chunk. The data in the chunk is only valid
@@ -73,37 +163,149 @@ func (r *Reader) ReadChunk() (*Chunk, error) {
 			}
 		}
 	}
-	// Make sure to read the pad byte if present.
+	// Make sure to read the pad byte if present. Some writers leave it off
+	// the last chunk in the file, so it's fine to hit the end instead.
 	if r.hdr.pad {
-		_, err := r.r.Read(r.scratch[:1])
-		if err == io.EOF {
-			return nil, errors.New("unexpected EOF: missing pad byte")
-		}
-		if err != nil {
+		if _, err := io.ReadFull(r.r, r.scratch[:1]); err != nil {
 			return nil, err
 		}
 	}
 
 	// Now we're ready to read the next chunk.
-	if err := readChunkHeader(r.r, &r.hdr); err != nil {
+	if err := readChunkHeader(r.r, r.ByteOrder, &r.hdr); err != nil {
 		return nil, err
 	}
+	r.fixSize(&r.hdr)
 	r.chunk.Identifier = string(r.hdr.id[:])
-	r.chunk.Size = int(r.hdr.size)
+	r.chunk.Size = int64(r.hdr.size)
 
 	r.chunk.Reader = &io.LimitedReader{R: r.r, N: int64(r.hdr.size)}
 
 	return &r.chunk, nil
 }
 
+// ReadUntil reads chunks until it finds one with the provided identifier,
+// discarding any chunks before it. It only reads forwards, so it works on
+// streams that can't seek. If the end of the file is reached before finding the
+// chunk it returns an error wrapping io.ErrUnexpectedEOF.
+func (r *Reader) ReadUntil(id string) (*Chunk, error) {
+	for {
+		c, err := r.ReadChunk()
+		if err == io.EOF {
+			return nil, fmt.Errorf("finding %q chunk: %w", id, io.ErrUnexpectedEOF)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if c.Identifier == id {
+			return c, nil
+		}
+	}
+}
+
+// SeekChunk abandons whatever is left of the current chunk and moves to offset
+// in the file, which should be the start of a chunk header, so the next call to
+// ReadChunk reads the chunk there. It is meant for recovering from corrupt
+// chunks, and needs the io.Reader passed to NewReader to also be an io.Seeker.
+func (r *Reader) SeekChunk(offset int64) error {
+	s, ok := r.r.(io.Seeker)
+	if !ok {
+		return errors.New("riff: SeekChunk needs an io.Seeker")
+	}
+	if _, err := s.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	r.chunk.Reader = nil
+	r.hdr.pad = false
+	return nil
+}
+
+// ChunkInfo describes where a chunk is in a file, without its contents.
+type ChunkInfo struct {
+	// Identifier is the 4 byte ASCII identifier for the chunk.
+	Identifier string
+	// Size is the number of bytes in the chunk.
+	Size int64
+	// Offset is the position of the start of the chunk's data in the
+	// file, just after its header.
+	Offset int64
+}
+
+// Chunks returns the identifier, size and position of all the remaining chunks
+// in the file, skipping over their contents by seeking rather than reading
+// them, so it is fast even for very large files. It needs the io.Reader
+// passed to NewReader to also be an io.Seeker. Afterwards, the Reader is at the
+// end of the file.
+func (r *Reader) Chunks() ([]ChunkInfo, error) {
+	s, ok := r.r.(io.Seeker)
+	if !ok {
+		return nil, errors.New("riff: Chunks needs an io.Seeker")
+	}
+	// Skip whatever is left of the current chunk.
+	var skip int64
+	if lr, ok := r.chunk.Reader.(*io.LimitedReader); ok {
+		skip = lr.N
+	}
+	if r.hdr.pad {
+		skip++
+	}
+	r.chunk.Reader = nil
+	r.hdr.pad = false
+	if _, err := s.Seek(skip, io.SeekCurrent); err != nil {
+		return nil, err
+	}
+
+	var (
+		chunks []ChunkInfo
+		hdr    chunkHeader
+	)
+	for {
+		if err := readChunkHeader(r.r, r.ByteOrder, &hdr); err == io.EOF {
+			return chunks, nil
+		} else if err != nil {
+			return nil, err
+		}
+		r.fixSize(&hdr)
+		offset, err := s.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, ChunkInfo{
+			Identifier: string(hdr.id[:]),
+			Size:       int64(hdr.size),
+			Offset:     offset,
+		})
+		skip := int64(hdr.size)
+		if hdr.pad {
+			skip++
+		}
+		if _, err := s.Seek(skip, io.SeekCurrent); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// fixSize replaces the size in ch with the one from the ds64 chunk, if the file
+// is RF64 and the size didn't fit in 32 bits.
+func (r *Reader) fixSize(ch *chunkHeader) {
+	if ch.size != math.MaxUint32 {
+		return
+	}
+	if size, ok := r.sizes[ch.id]; ok {
+		ch.size = size
+		ch.pad = size%2 == 1
+	}
+}
+
 type chunkHeader struct {
 	id   [4]byte
-	size uint32
+	size uint64
 	pad  bool // true if we need to read one extra padding byte
 }
 
-// readChunkHeader populates the provided chunkHeader from the given reader.
-func readChunkHeader(r io.Reader, ch *chunkHeader) error {
+// readChunkHeader populates the provided chunkHeader from the given reader,
+// decoding the size with the given byte order.
+func readChunkHeader(r io.Reader, order binary.ByteOrder, ch *chunkHeader) error {
 	if ch == nil {
 		// should not be possible.
 		return errors.New("nil chunkHeader")
@@ -117,26 +319,56 @@ func readChunkHeader(r io.Reader, ch *chunkHeader) error {
 	if _, err := io.ReadFull(r, rawSize[:]); err != nil {
 		return err
 	}
-	ch.size = binary.LittleEndian.Uint32(rawSize[:])
+	ch.size = uint64(order.Uint32(rawSize[:]))
 	// There will be padding if the size is an odd number.
 	ch.pad = ch.size%2 == 1
 	return nil
 }
 
+// ErrTooLarge is returned when a chunk, or the whole file, is too big for its
+// size to fit in the 32 bits RIFF allows. Files that big need a format like
+// RF64 instead.
+var ErrTooLarge = errors.New("riff: size does not fit in 32 bits")
+
 // Writer writes RIFF files.
 type Writer struct {
 	ws io.WriteSeeker
+	// order is the byte order sizes are written in.
+	order binary.AppendByteOrder
 	// written is the number of bytes written into the overall RIFF chunk.
-	written uint32
+	written int64
 
 	scratch []byte
 }
 
+// WriterOption configures optional behaviour of a Writer.
+type WriterOption func(*Writer)
+
+// WithRIFX makes the Writer write a big-endian RIFX file instead of a RIFF
+// file. Only the sizes of the chunks are affected, the caller is responsible
+// for the byte order of anything inside them.
+func WithRIFX() WriterOption {
+	return func(w *Writer) {
+		w.order = binary.BigEndian
+	}
+}
+
 // NewWriter constructs a new Writer, ready to write RIFF chunks.
-func NewWriter(ws io.WriteSeeker, form string) (*Writer, error) {
+func NewWriter(ws io.WriteSeeker, form string, opts ...WriterOption) (*Writer, error) {
+	w := &Writer{
+		ws:      ws,
+		order:   binary.LittleEndian,
+		written: 4, // The form counts.
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
 	// First write the RIFF header, the form id and empty space
 	// for the size.
 	hdr := []byte{'R', 'I', 'F', 'F'}
+	if w.order == binary.BigEndian {
+		hdr = []byte{'R', 'I', 'F', 'X'}
+	}
 	if len(form) != 4 {
 		return nil, fmt.Errorf("invalid form ID: %q", form)
 	}
@@ -146,10 +378,7 @@ func NewWriter(ws io.WriteSeeker, form string) (*Writer, error) {
 	if _, err := ws.Write(hdr); err != nil {
 		return nil, err
 	}
-	return &Writer{
-		ws:      ws,
-		written: 4, // The form counts.
-	}, nil
+	return w, nil
 }
 
 // WriteChunk writes appropriate chunk metadata, and copies all the data from
@@ -159,6 +388,9 @@ func (w *Writer) WriteChunk(c *Chunk) error {
 	if len(c.Identifier) != 4 {
 		return fmt.Errorf("invalid chunk identifier: %q", c.Identifier)
 	}
+	if c.Size < 0 || c.Size > math.MaxUint32 {
+		return fmt.Errorf("chunk %q of size %d: %w", c.Identifier, c.Size, ErrTooLarge)
+	}
 	if err := w.write([]byte(c.Identifier)); err != nil {
 		return err
 	}
@@ -166,7 +398,7 @@ func (w *Writer) WriteChunk(c *Chunk) error {
 		return err
 	}
 	n, err := io.Copy(w.ws, c.Reader)
-	w.written += uint32(n)
+	w.written += n
 	if err != nil {
 		return err
 	}
@@ -182,25 +414,30 @@ func (w *Writer) WriteChunk(c *Chunk) error {
 // counter by the number of bytes written.
 func (w *Writer) write(p []byte) error {
 	n, err := w.ws.Write(p)
-	w.written += uint32(n)
+	w.written += int64(n)
 	return err
 }
 
 // Close closes the writer and finalizes the metadata. It does not close the
-// underlying writer but it does seek it back somewhere near the beginning.
+// underlying writer but it does seek it back somewhere near the beginning. If
+// too much has been written for the size to fit in the RIFF header it returns
+// an error wrapping ErrTooLarge.
 func (w *Writer) Close() error {
+	if w.written > math.MaxUint32 {
+		return fmt.Errorf("RIFF chunk of size %d: %w", w.written, ErrTooLarge)
+	}
 	// All we need to write is the size.
 	if _, err := w.ws.Seek(4, io.SeekStart); err != nil {
 		return err
 	}
-	_, err := w.ws.Write(w.uint32(w.written))
+	_, err := w.ws.Write(w.uint32(uint32(w.written)))
 	return err
 }
 
 // uint32 encodes a uint32 appropriately into w.scratch and returns the slice.
 // The data is only valid until the next time someone uses w.scratch.
 func (w *Writer) uint32(u uint32) []byte {
-	return binary.LittleEndian.AppendUint32(w.getScratch(4)[:0], u)
+	return w.order.AppendUint32(w.getScratch(4)[:0], u)
 }
 
 func (w *Writer) getScratch(n int) []byte {
@@ -230,7 +467,7 @@ func (w *Writer) NewChunk(identifier string) (io.WriteCloser, error) {
 // bytes have been written to the chunk.
 type chunkWriter struct {
 	w       *Writer
-	written uint32
+	written int64
 }
 
 func newChunkWriter(w *Writer) *chunkWriter {
@@ -239,18 +476,21 @@ func newChunkWriter(w *Writer) *chunkWriter {
 
 func (c *chunkWriter) Write(p []byte) (int, error) {
 	n, err := c.w.ws.Write(p)
-	c.written += uint32(n)
+	c.written += int64(n)
 	return n, err
 }
 
 func (c *chunkWriter) Close() error {
+	if c.written > math.MaxUint32 {
+		return fmt.Errorf("chunk of size %d: %w", c.written, ErrTooLarge)
+	}
 	// Seek back 4 bytes further than we have written to overwrite the empty
 	// size that we wrote when the chunk was opened.
-	if _, err := c.w.ws.Seek(-(int64(c.written) + 4), io.SeekCurrent); err != nil {
+	if _, err := c.w.ws.Seek(-(c.written + 4), io.SeekCurrent); err != nil {
 		return err
 	}
 	// Write the size.
-	if _, err := c.w.ws.Write(c.w.uint32(c.written)); err != nil {
+	if _, err := c.w.ws.Write(c.w.uint32(uint32(c.written))); err != nil {
 		return err
 	}
 	// seek back to the end
@@ -2,6 +2,8 @@ package riff
 
 import (
 	"bytes"
+	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 	"math/rand/v2"
@@ -10,6 +12,7 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/pfcm/audiofile/internal/testio"
 )
 
 func TestRoundTrip(t *testing.T) {
@@ -66,7 +69,7 @@ func TestRoundTrip(t *testing.T) {
 	for _, c := range chunks {
 		chnk := &Chunk{
 			Identifier: c.id,
-			Size:       len(c.data),
+			Size:       int64(len(c.data)),
 			Reader:     bytes.NewReader(c.data),
 		}
 		if err := w.WriteChunk(chnk); err != nil {
@@ -105,7 +108,7 @@ func TestRoundTrip(t *testing.T) {
 		if err != nil {
 			t.Fatal(err)
 		}
-		if len(data) != chnk.Size {
+		if int64(len(data)) != chnk.Size {
 			t.Fatalf("Size mismatch: Chunk %d has size %x, read %x bytes", i, chnk.Size, len(data))
 		}
 		got = append(got, chunk{
@@ -124,3 +127,474 @@ func TestRoundTrip(t *testing.T) {
 		}
 	}
 }
+
+func TestReadUntil(t *testing.T) {
+	pr, pw := io.Pipe()
+	// Write to a file first, because the Writer needs to seek, then copy
+	// it through a pipe so the reader can't.
+	path := filepath.Join(t.TempDir(), "test.riff")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewWriter(f, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, id := range []string{"aaaa", "bbbb", "cccc", "want", "dddd"} {
+		data := bytes.Repeat([]byte{byte(i)}, 100+i)
+		if err := w.WriteChunk(&Chunk{
+			Identifier: id,
+			Size:       int64(len(data)),
+			Reader:     bytes.NewReader(data),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		_, err := io.Copy(pw, f)
+		pw.CloseWithError(err)
+		f.Close()
+	}()
+
+	r, err := NewReader(pr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := r.ReadUntil("want")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff(data, bytes.Repeat([]byte{3}, 103)); d != "" {
+		t.Errorf("chunk data mismatch (-got, +want):\n%v", d)
+	}
+
+	if _, err := r.ReadUntil("none"); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("ReadUntil(missing chunk): got error %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestWriteTooLarge(t *testing.T) {
+	w, err := NewWriter(&testio.DiscardSeeker{}, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cw, err := w.NewChunk("big ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Write just over 4GB, a megabyte at a time.
+	buf := make([]byte, 1<<20)
+	for range 1<<12 + 1 {
+		if _, err := cw.Write(buf); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := cw.Close(); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("closing chunk: got error %v, want %v", err, ErrTooLarge)
+	}
+
+	// A chunk that fits, but makes the whole file too big.
+	w, err = NewWriter(&testio.DiscardSeeker{}, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		cw, err := w.NewChunk("big ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for range 1 << 11 {
+			if _, err := cw.Write(buf); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := cw.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("closing writer: got error %v, want %v", err, ErrTooLarge)
+	}
+}
+
+func TestOddFinalChunk(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		pad  []byte
+	}{
+		{"with pad byte", []byte{0}},
+		{"without pad byte", nil},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			body := append([]byte("test"+"abcd\x03\x00\x00\x00xyz"), c.pad...)
+			raw := binary.LittleEndian.AppendUint32([]byte("RIFF"), uint32(len(body)))
+			raw = append(raw, body...)
+
+			r, err := NewReader(bytes.NewReader(raw))
+			if err != nil {
+				t.Fatal(err)
+			}
+			chunk, err := r.ReadChunk()
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := io.ReadAll(chunk)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != "xyz" {
+				t.Errorf("chunk data: got %q, want %q", data, "xyz")
+			}
+			if _, err := r.ReadChunk(); err != io.EOF {
+				t.Errorf("reading past the last chunk: got error %v, want %v", err, io.EOF)
+			}
+		})
+	}
+}
+
+func TestRIFXRoundTrip(t *testing.T) {
+	chunks := []struct {
+		id   string
+		data []byte
+	}{
+		{"abcd", []byte("some data")},
+		{"efgh", bytes.Repeat([]byte{7}, 300)},
+	}
+	path := filepath.Join(t.TempDir(), "test.rifx")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewWriter(f, "test", WithRIFX())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// One chunk each way.
+	if err := w.WriteChunk(&Chunk{
+		Identifier: chunks[0].id,
+		Size:       int64(len(chunks[0].data)),
+		Reader:     bytes.NewReader(chunks[0].data),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	cw, err := w.NewChunk(chunks[1].id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cw.Write(chunks[1].data); err != nil {
+		t.Fatal(err)
+	}
+	if err := cw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 4 for the form, then each chunk has an 8 byte header and the odd
+	// one has a pad byte.
+	wantSize := 4 + 8 + 9 + 1 + 8 + 300
+	if d := cmp.Diff(raw[:12], append(binary.BigEndian.AppendUint32([]byte("RIFX"), uint32(wantSize)), "test"...)); d != "" {
+		t.Errorf("RIFX header mismatch (-got, +want):\n%v", d)
+	}
+	if got := binary.BigEndian.Uint32(raw[16:]); got != 9 {
+		t.Errorf("first chunk size = %d, want 9", got)
+	}
+
+	r, err := NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.ByteOrder != binary.BigEndian {
+		t.Errorf("ByteOrder = %v, want big-endian", r.ByteOrder)
+	}
+	for _, want := range chunks {
+		c, err := r.ReadChunk()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.Identifier != want.id || c.Size != int64(len(want.data)) {
+			t.Errorf("got chunk %q with size %d, want %q with size %d", c.Identifier, c.Size, want.id, len(want.data))
+		}
+		data, err := io.ReadAll(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d := cmp.Diff(data, want.data); d != "" {
+			t.Errorf("chunk %q data mismatch (-got, +want):\n%v", want.id, d)
+		}
+	}
+	if _, err := r.ReadChunk(); err != io.EOF {
+		t.Errorf("ReadChunk at the end: got %v, want EOF", err)
+	}
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	*bytes.Reader
+	read int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.Reader.Read(p)
+	c.read += n
+	return n, err
+}
+
+func TestChunks(t *testing.T) {
+	chunks := []struct {
+		id   string
+		data []byte
+	}{
+		{"fmt ", bytes.Repeat([]byte{1}, 16)},
+		{"odd ", bytes.Repeat([]byte{2}, 9)},
+		{"data", bytes.Repeat([]byte{3}, 100000)},
+		{"last", bytes.Repeat([]byte{4}, 3)},
+	}
+	path := filepath.Join(t.TempDir(), "test.riff")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewWriter(f, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range chunks {
+		if err := w.WriteChunk(&Chunk{
+			Identifier: c.id,
+			Size:       int64(len(c.data)),
+			Reader:     bytes.NewReader(c.data),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cr := &countingReader{Reader: bytes.NewReader(raw)}
+	r, err := NewReader(cr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Start part way through the first chunk.
+	c, err := r.ReadChunk()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadFull(c, make([]byte, 4)); err != nil {
+		t.Fatal(err)
+	}
+	got, err := r.Chunks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(chunks)-1 {
+		t.Fatalf("Chunks() returned %d chunks, want %d", len(got), len(chunks)-1)
+	}
+	for i, info := range got {
+		want := chunks[i+1]
+		if info.Identifier != want.id || info.Size != int64(len(want.data)) {
+			t.Errorf("chunk %d: got %q with size %d, want %q with size %d", i, info.Identifier, info.Size, want.id, len(want.data))
+			continue
+		}
+		if d := cmp.Diff(raw[info.Offset:info.Offset+info.Size], want.data); d != "" {
+			t.Errorf("chunk %q data at offset %d mismatch (-got, +want):\n%v", info.Identifier, info.Offset, d)
+		}
+	}
+	if cr.read > 100 {
+		t.Errorf("Chunks() read %d bytes, expected it to seek over the data", cr.read)
+	}
+	if _, err := r.ReadChunk(); err != io.EOF {
+		t.Errorf("ReadChunk after Chunks: got %v, want EOF", err)
+	}
+
+	r, err = NewReader(bytes.NewBuffer(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Chunks(); err == nil {
+		t.Error("Chunks without a Seeker: expected error")
+	}
+}
+
+func TestRF64(t *testing.T) {
+	le := binary.LittleEndian
+	hdr := func(id string, size uint32) []byte {
+		return le.AppendUint32([]byte(id), size)
+	}
+	// A ds64 chunk with the real data size, and a table entry for another
+	// oversized chunk.
+	ds64 := le.AppendUint64(nil, 0) // RIFF size, ignored.
+	ds64 = le.AppendUint64(ds64, 6) // data size
+	ds64 = le.AppendUint64(ds64, 3) // sample count
+	ds64 = le.AppendUint32(ds64, 1) // table length
+	ds64 = append(ds64, "big "...)
+	ds64 = le.AppendUint64(ds64, 3)
+
+	var raw []byte
+	raw = append(raw, hdr("RF64", 0xFFFFFFFF)...)
+	raw = append(raw, "WAVE"...)
+	raw = append(raw, hdr("ds64", uint32(len(ds64)))...)
+	raw = append(raw, ds64...)
+	raw = append(raw, hdr("fmt ", 2)...)
+	raw = append(raw, 1, 2)
+	raw = append(raw, hdr("data", 0xFFFFFFFF)...)
+	raw = append(raw, 3, 4, 5, 6, 7, 8)
+	raw = append(raw, hdr("big ", 0xFFFFFFFF)...)
+	raw = append(raw, 9, 10, 11, 0) // padded to an even length
+	raw = append(raw, hdr("last", 1)...)
+	raw = append(raw, 12)
+
+	type chunk struct {
+		ID   string
+		Size int64
+		Data []byte
+	}
+	want := []chunk{
+		{"fmt ", 2, []byte{1, 2}},
+		{"data", 6, []byte{3, 4, 5, 6, 7, 8}},
+		{"big ", 3, []byte{9, 10, 11}},
+		{"last", 1, []byte{12}},
+	}
+	for _, id := range []string{"RF64", "BW64"} {
+		t.Run(id, func(t *testing.T) {
+			raw := bytes.Clone(raw)
+			copy(raw, id)
+			r, err := NewReader(bytes.NewReader(raw))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r.Form != "WAVE" {
+				t.Errorf("Form = %q, want WAVE", r.Form)
+			}
+			var got []chunk
+			for {
+				c, err := r.ReadChunk()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				data, err := io.ReadAll(c)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, chunk{c.Identifier, c.Size, data})
+			}
+			if d := cmp.Diff(got, want); d != "" {
+				t.Errorf("chunks mismatch (-got, +want):\n%v", d)
+			}
+		})
+	}
+
+	// The ds64 chunk has to come first.
+	bad := append(hdr("RF64", 0xFFFFFFFF), "WAVE"...)
+	bad = append(bad, hdr("fmt ", 2)...)
+	bad = append(bad, 1, 2)
+	if _, err := NewReader(bytes.NewReader(bad)); err == nil {
+		t.Error("NewReader on RF64 without ds64: expected error")
+	}
+
+	// Sizes that don't fit in 32 bits come through whole, even if there
+	// isn't that much data.
+	const huge = 5 << 30
+	ds64 = le.AppendUint64(nil, 0)
+	ds64 = le.AppendUint64(ds64, huge)
+	ds64 = le.AppendUint64(ds64, 0)
+	ds64 = le.AppendUint32(ds64, 0)
+	big := append(hdr("RF64", 0xFFFFFFFF), "WAVE"...)
+	big = append(big, hdr("ds64", uint32(len(ds64)))...)
+	big = append(big, ds64...)
+	big = append(big, hdr("data", 0xFFFFFFFF)...)
+	r, err := NewReader(bytes.NewReader(big))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := r.ReadChunk()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Size != huge {
+		t.Errorf("data chunk Size = %d, want %d", c.Size, int64(huge))
+	}
+
+	// A ds64 chunk claiming to be enormous is rejected before trying to
+	// read it.
+	bad = append(hdr("RF64", 0xFFFFFFFF), "WAVE"...)
+	bad = append(bad, hdr("ds64", 0xFFFFFFF0)...)
+	bad = append(bad, make([]byte, 28)...)
+	if _, err := NewReader(bytes.NewReader(bad)); err == nil {
+		t.Error("NewReader with a huge ds64 chunk: expected error")
+	}
+}
+
+func TestReadIFF(t *testing.T) {
+	var raw []byte
+	raw = binary.BigEndian.AppendUint32(append(raw, "FORM"...), 4+8+3+1+8+2)
+	raw = append(raw, "AIFF"...)
+	raw = binary.BigEndian.AppendUint32(append(raw, "abcd"...), 3)
+	raw = append(raw, 1, 2, 3, 0)
+	raw = binary.BigEndian.AppendUint32(append(raw, "efgh"...), 2)
+	raw = append(raw, 4, 5)
+
+	r, err := NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Form != "AIFF" || r.ByteOrder != binary.BigEndian {
+		t.Errorf("got form %q with byte order %v, want %q big-endian", r.Form, r.ByteOrder, "AIFF")
+	}
+	for _, want := range []struct {
+		id   string
+		data []byte
+	}{
+		{"abcd", []byte{1, 2, 3}},
+		{"efgh", []byte{4, 5}},
+	} {
+		c, err := r.ReadChunk()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.Identifier != want.id {
+			t.Errorf("got chunk %q, want %q", c.Identifier, want.id)
+		}
+		if d := cmp.Diff(data, want.data); d != "" {
+			t.Errorf("chunk %q data mismatch (-got, +want):\n%v", want.id, d)
+		}
+	}
+	if _, err := r.ReadChunk(); err != io.EOF {
+		t.Errorf("ReadChunk at the end: got %v, want EOF", err)
+	}
+}
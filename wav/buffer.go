@@ -0,0 +1,54 @@
+package wav
+
+// AudioBuffer holds decoded audio along with the format of the file it came
+// from.
+type AudioBuffer struct {
+	// Format is the format of the file the audio was read from. The audio
+	// in Data has always been converted to 32 bit floats.
+	Format FileFormat
+	// Data holds the samples for each channel.
+	Data [][]float32
+}
+
+// NumFrames returns the number of samples in each channel.
+func (b *AudioBuffer) NumFrames() int {
+	if len(b.Data) == 0 {
+		return 0
+	}
+	return len(b.Data[0])
+}
+
+// Channel returns the samples for channel n. It panics if n is out of range.
+func (b *AudioBuffer) Channel(n int) []float32 {
+	return b.Data[n]
+}
+
+// Interleaved returns a new slice holding the samples of every channel
+// interleaved, the way they are stored in a file.
+func (b *AudioBuffer) Interleaved() []float32 {
+	out := make([]float32, 0, b.NumFrames()*len(b.Data))
+	for i := range b.NumFrames() {
+		for _, ch := range b.Data {
+			out = append(out, ch[i])
+		}
+	}
+	return out
+}
+
+// ReadAll reads all the audio data into an AudioBuffer, converting it to 32 bit
+// floats like ReadFull32Float.
+func (r *Reader) ReadAll() (*AudioBuffer, error) {
+	data, err := ReadFull32Float(r)
+	if err != nil {
+		return nil, err
+	}
+	return &AudioBuffer{
+		Format: FileFormat{
+			Format:     r.Format(),
+			BitDepth:   r.BitDepth(),
+			Channels:   r.Channels(),
+			SampleRate: r.Samplerate(),
+		},
+		Data: data,
+	}, nil
+}
@@ -0,0 +1,39 @@
+package wav
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAudioBuffer(t *testing.T) {
+	ff := FileFormat{Format: PCM, BitDepth: 16, Channels: 2, SampleRate: 22050}
+	raw := write16PCM(t, ff, [][]int16{
+		{0, 32767, -32767},
+		{32767, 0, 0},
+	})
+	r, err := NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := r.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Format != ff {
+		t.Errorf("Format = %+v, want %+v", b.Format, ff)
+	}
+	if got := b.NumFrames(); got != 3 {
+		t.Errorf("NumFrames() = %d, want 3", got)
+	}
+	if d := cmp.Diff(b.Channel(0), []float32{0, 1, -1}); d != "" {
+		t.Errorf("Channel(0) mismatch (-got, +want):\n%v", d)
+	}
+	if d := cmp.Diff(b.Channel(1), []float32{1, 0, 0}); d != "" {
+		t.Errorf("Channel(1) mismatch (-got, +want):\n%v", d)
+	}
+	if d := cmp.Diff(b.Interleaved(), []float32{0, 1, 1, 0, -1, 0}); d != "" {
+		t.Errorf("Interleaved() mismatch (-got, +want):\n%v", d)
+	}
+}
@@ -0,0 +1,103 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// CartChunk holds the radio traffic metadata from a cart chunk, as described in
+// AES46. Text fields have any trailing null padding removed.
+type CartChunk struct {
+	// Version is the version of the cart chunk format, eg. "0101".
+	Version        string
+	Title          string
+	Artist         string
+	CutID          string
+	ClientID       string
+	Category       string
+	Classification string
+	OutCue         string
+	// StartDate and EndDate are formatted yyyy-mm-dd, StartTime and
+	// EndTime are hh:mm:ss.
+	StartDate          string
+	StartTime          string
+	EndDate            string
+	EndTime            string
+	ProducerAppID      string
+	ProducerAppVersion string
+	UserDef            string
+	// LevelReference is the sample value of 0dB.
+	LevelReference int32
+	// PostTimers are the timers that have been set, unused timers are
+	// left out.
+	PostTimers []CartTimer
+	URL        string
+	// TagText is free form text, usually CR/LF separated.
+	TagText string
+}
+
+// CartTimer is a marker in a cart chunk.
+type CartTimer struct {
+	// Usage is the four character code of the timer, eg. "SEC1" or "EOD".
+	Usage string
+	// Value is the position of the timer, in samples from the start of
+	// the audio.
+	Value uint32
+}
+
+// cartFixedSize is the size of all of the fields before the tag text.
+const cartFixedSize = 2048
+
+// Cart returns the contents of the file's cart chunk, or nil if it doesn't have
+// one. Only chunks before the audio data are searched.
+func (r *Reader) Cart() (*CartChunk, error) {
+	for _, mc := range r.metadata {
+		if mc.id == "cart" {
+			return parseCart(mc.data)
+		}
+	}
+	return nil, nil
+}
+
+func parseCart(raw []byte) (*CartChunk, error) {
+	if len(raw) < cartFixedSize {
+		return nil, fmt.Errorf("cart chunk too short: %d bytes, need at least %d", len(raw), cartFixedSize)
+	}
+	str := func(n int) string {
+		s := string(bytes.TrimRight(raw[:n], "\x00"))
+		raw = raw[n:]
+		return s
+	}
+	var c CartChunk
+	c.Version = str(4)
+	c.Title = str(64)
+	c.Artist = str(64)
+	c.CutID = str(64)
+	c.ClientID = str(64)
+	c.Category = str(64)
+	c.Classification = str(64)
+	c.OutCue = str(64)
+	c.StartDate = str(10)
+	c.StartTime = str(8)
+	c.EndDate = str(10)
+	c.EndTime = str(8)
+	c.ProducerAppID = str(64)
+	c.ProducerAppVersion = str(64)
+	c.UserDef = str(64)
+	c.LevelReference = int32(binary.LittleEndian.Uint32(raw))
+	raw = raw[4:]
+	for range 8 {
+		usage := str(4)
+		value := binary.LittleEndian.Uint32(raw)
+		raw = raw[4:]
+		if usage != "" {
+			c.PostTimers = append(c.PostTimers, CartTimer{Usage: usage, Value: value})
+		}
+	}
+	// Reserved.
+	raw = raw[276:]
+	c.URL = str(1024)
+	c.TagText = str(len(raw))
+	return &c, nil
+}
@@ -0,0 +1,93 @@
+package wav
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// padded returns s padded with nulls to n bytes.
+func padded(s string, n int) []byte {
+	b := make([]byte, n)
+	copy(b, s)
+	return b
+}
+
+func TestCart(t *testing.T) {
+	cart := cat(
+		[]byte("0101"),
+		padded("Morning Jingle", 64),
+		padded("The Station", 64),
+		padded("CUT0042", 64),
+		padded("ACME", 64),
+		padded("JINGLE", 64),
+		padded("", 64),
+		padded("fade", 64),
+		[]byte("2024-01-02"),
+		[]byte("06:00:00"),
+		[]byte("2024-12-31"),
+		[]byte("23:59:59"),
+		padded("Automation", 64),
+		padded("1.2", 64),
+		padded("", 64),
+		uint32le(0x7FFF),
+		// Post timers, only two used.
+		[]byte("SEC1"), uint32le(1000),
+		padded("EOD", 4), uint32le(44100),
+		make([]byte, 6*8),
+		make([]byte, 276),
+		padded("https://example.com/cut/42", 1024),
+		[]byte("line one\r\nline two\r\n"),
+	)
+	want := &CartChunk{
+		Version:            "0101",
+		Title:              "Morning Jingle",
+		Artist:             "The Station",
+		CutID:              "CUT0042",
+		ClientID:           "ACME",
+		Category:           "JINGLE",
+		OutCue:             "fade",
+		StartDate:          "2024-01-02",
+		StartTime:          "06:00:00",
+		EndDate:            "2024-12-31",
+		EndTime:            "23:59:59",
+		ProducerAppID:      "Automation",
+		ProducerAppVersion: "1.2",
+		LevelReference:     0x7FFF,
+		PostTimers: []CartTimer{
+			{Usage: "SEC1", Value: 1000},
+			{Usage: "EOD", Value: 44100},
+		},
+		URL:     "https://example.com/cut/42",
+		TagText: "line one\r\nline two\r\n",
+	}
+
+	r, err := NewReader(bytes.NewReader(writeRIFF(t, "WAVE",
+		rawChunk("fmt ", pcm16Fmt()),
+		rawChunk("cart", cart),
+		rawChunk("data", []byte{0, 0}),
+	)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := r.Cart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff(got, want); d != "" {
+		t.Errorf("Cart() mismatch (-got, +want):\n%v", d)
+	}
+
+	// And without one.
+	r, err = NewReader(bytes.NewReader(writeRIFF(t, "WAVE",
+		rawChunk("fmt ", pcm16Fmt()),
+		rawChunk("data", []byte{0, 0}),
+	)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := r.Cart(); got != nil || err != nil {
+		t.Errorf("Cart() without a cart chunk: got %v, %v, want nil, nil", got, err)
+	}
+}
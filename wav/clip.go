@@ -0,0 +1,50 @@
+package wav
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// Clip copies the audio between start and end in src into a new wav file in
+// dst, with exactly the same format. The samples are copied without being
+// decoded. If end is past the end of the audio, the clip stops at the end.
+func Clip(dst io.WriteSeeker, src io.ReadSeeker, start, end time.Duration) error {
+	if start < 0 || end < start {
+		return fmt.Errorf("invalid clip from %v to %v", start, end)
+	}
+	r, err := NewReader(src)
+	if err != nil {
+		return err
+	}
+	first, last := r.sampleAt(start), r.sampleAt(end)
+	if n := r.Samples(); n >= 0 {
+		last = min(last, n)
+	}
+	first = min(first, last)
+	blockAlign := int64(r.fmt.blockAlign)
+
+	// Skip everything before the clip.
+	if _, err := io.CopyN(io.Discard, r, int64(first)*blockAlign); err != nil {
+		return err
+	}
+	w, err := r.EquivalentWriter(dst)
+	if err != nil {
+		return err
+	}
+	// If the length isn't known, running out early just means end was past
+	// the end of the audio.
+	if _, err := io.CopyN(w, r, int64(last-first)*blockAlign); err != nil && (err != io.EOF || r.Samples() >= 0) {
+		return err
+	}
+	return w.Close()
+}
+
+// sampleAt returns the index of the sample at time d.
+func (r *Reader) sampleAt(d time.Duration) int {
+	// Split into whole seconds and the remainder, so the multiplication
+	// can't overflow.
+	secs, rem := d/time.Second, d%time.Second
+	rate := time.Duration(r.fmt.sampleRate)
+	return int(secs*rate + rem*rate/time.Second)
+}
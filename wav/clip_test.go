@@ -0,0 +1,58 @@
+package wav
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestClip(t *testing.T) {
+	const rate = 1000
+	samples := makeSlices[int16](2, 3*rate)
+	for i := range samples[0] {
+		samples[0][i] = int16(i)
+		samples[1][i] = int16(-i)
+	}
+	raw := write16PCM(t, FileFormat{
+		Format:     PCM,
+		BitDepth:   16,
+		Channels:   2,
+		SampleRate: rate,
+	}, samples)
+
+	path := filepath.Join(t.TempDir(), "clip.wav")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Clip(f, bytes.NewReader(raw), time.Second, 2*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	clipped, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewReader(bytes.NewReader(clipped))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := r.Samplerate(); got != rate {
+		t.Errorf("Samplerate() = %d, want %d", got, rate)
+	}
+	got, err := ReadFull16PCM(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]int16{samples[0][rate : 2*rate], samples[1][rate : 2*rate]}
+	if d := cmp.Diff(got, want); d != "" {
+		t.Errorf("clipped samples mismatch (-got, +want):\n%v", d)
+	}
+}
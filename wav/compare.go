@@ -0,0 +1,80 @@
+package wav
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Mismatch describes the first sample that differs between two files compared
+// with CompareAudio.
+type Mismatch struct {
+	// Channel and Sample are the position of the sample.
+	Channel, Sample int
+	// A and B are the values of the sample in each file, as 64 bit
+	// floats.
+	A, B float64
+}
+
+func (m *Mismatch) Error() string {
+	return fmt.Sprintf("channel %d sample %d differs: %v vs %v", m.Channel, m.Sample, m.A, m.B)
+}
+
+// compareFrames is the number of frames CompareAudio decodes at a time.
+const compareFrames = 4096
+
+// CompareAudio reports whether the wav files in a and b hold the same audio,
+// with every sample within tolerance of each other once decoded to 64 bit
+// floats. The files can have different sample formats, but must have the same
+// number of channels, sample rate and length. If they differ, the returned
+// error describes how: if the shapes match it is a *Mismatch giving the first
+// sample that is too different. Other errors come from reading the files.
+func CompareAudio(a, b io.Reader, tolerance float64) (bool, error) {
+	ra, err := NewReader(a)
+	if err != nil {
+		return false, err
+	}
+	rb, err := NewReader(b)
+	if err != nil {
+		return false, err
+	}
+	if ra.Channels() != rb.Channels() {
+		return false, fmt.Errorf("channels differ: %d vs %d", ra.Channels(), rb.Channels())
+	}
+	if ra.Samplerate() != rb.Samplerate() {
+		return false, fmt.Errorf("sample rates differ: %d vs %d", ra.Samplerate(), rb.Samplerate())
+	}
+	if ra.Samples() < 0 || rb.Samples() < 0 {
+		return false, errors.New("can't compare audio of unknown length")
+	}
+	if ra.Samples() != rb.Samples() {
+		return false, fmt.Errorf("lengths differ: %d vs %d samples", ra.Samples(), rb.Samples())
+	}
+	var (
+		bufA = makeSlices[float64](ra.Channels(), compareFrames)
+		bufB = makeSlices[float64](rb.Channels(), compareFrames)
+	)
+	for offset := 0; offset < ra.Samples(); {
+		na, err := ra.Read64Float(bufA)
+		if err != nil {
+			return false, err
+		}
+		nb, err := rb.Read64Float(bufB)
+		if err != nil {
+			return false, err
+		}
+		if na != nb || na == 0 {
+			return false, fmt.Errorf("reading audio: got %d and %d samples: %w", na, nb, io.ErrUnexpectedEOF)
+		}
+		for c := range bufA {
+			for i := range na {
+				if x, y := bufA[c][i], bufB[c][i]; !(math.Abs(x-y) <= tolerance) {
+					return false, &Mismatch{Channel: c, Sample: offset + i, A: x, B: y}
+				}
+			}
+		}
+		offset += na
+	}
+	return true, nil
+}
@@ -0,0 +1,101 @@
+package wav
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCompareAudio(t *testing.T) {
+	const n = compareFrames + 100
+	ff := FileFormat{
+		Format:     PCM,
+		BitDepth:   16,
+		Channels:   2,
+		SampleRate: 44100,
+	}
+	samples := makeSlices[int16](2, n)
+	dithered := makeSlices[int16](2, n)
+	for i := range n {
+		samples[0][i] = int16(i * 5)
+		samples[1][i] = int16(-i * 3)
+	}
+	copy(dithered[0], samples[0])
+	copy(dithered[1], samples[1])
+	// Only change one sample, after the first buffer, by the smallest
+	// possible amount.
+	dithered[1][compareFrames+10]++
+	orig := write16PCM(t, ff, samples)
+	changed := write16PCM(t, ff, dithered)
+
+	t.Run("identical", func(t *testing.T) {
+		same, err := CompareAudio(bytes.NewReader(orig), bytes.NewReader(orig), 0)
+		if !same || err != nil {
+			t.Errorf("CompareAudio(a, a) = %v, %v, want true, nil", same, err)
+		}
+	})
+	t.Run("within tolerance", func(t *testing.T) {
+		same, err := CompareAudio(bytes.NewReader(orig), bytes.NewReader(changed), 1e-3)
+		if !same || err != nil {
+			t.Errorf("CompareAudio(a, dithered a, 1e-3) = %v, %v, want true, nil", same, err)
+		}
+	})
+	t.Run("differs", func(t *testing.T) {
+		same, err := CompareAudio(bytes.NewReader(orig), bytes.NewReader(changed), 1e-6)
+		if same {
+			t.Error("CompareAudio(a, dithered a, 1e-6) = true, want false")
+		}
+		var m *Mismatch
+		if !errors.As(err, &m) {
+			t.Fatalf("CompareAudio(a, dithered a, 1e-6) error = %v, want a *Mismatch", err)
+		}
+		want := &Mismatch{
+			Channel: 1,
+			Sample:  compareFrames + 10,
+			A:       from16PCMToFloat64(samples[1][compareFrames+10]),
+			B:       from16PCMToFloat64(dithered[1][compareFrames+10]),
+		}
+		if d := cmp.Diff(m, want); d != "" {
+			t.Errorf("mismatch (-got, +want):\n%v", d)
+		}
+	})
+	t.Run("different shape", func(t *testing.T) {
+		mono := write16PCM(t, FileFormat{
+			Format:     PCM,
+			BitDepth:   16,
+			Channels:   1,
+			SampleRate: 44100,
+		}, samples[:1])
+		same, err := CompareAudio(bytes.NewReader(orig), bytes.NewReader(mono), 1)
+		if same || err == nil {
+			t.Errorf("CompareAudio(stereo, mono) = %v, %v, want false and an error", same, err)
+		}
+	})
+	t.Run("A-law", func(t *testing.T) {
+		alaw := writeRIFF(t, "WAVE",
+			rawChunk("fmt ", cat(
+				uint16le(uint16(ALaw)),
+				uint16le(1),
+				uint32le(8000),
+				uint32le(8000),
+				uint16le(1),
+				uint16le(8),
+				uint16le(0),
+			)),
+			rawChunk("data", []byte{0xd5, 0x55, 0xaa, 0x2a}),
+		)
+		// The same samples, already expanded.
+		pcm := write16PCM(t, FileFormat{
+			Format:     PCM,
+			BitDepth:   16,
+			Channels:   1,
+			SampleRate: 8000,
+		}, [][]int16{{8, -8, 32256, -32256}})
+		same, err := CompareAudio(bytes.NewReader(alaw), bytes.NewReader(pcm), 0)
+		if !same || err != nil {
+			t.Errorf("CompareAudio(A-law, PCM) = %v, %v, want true, nil", same, err)
+		}
+	})
+}
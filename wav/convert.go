@@ -0,0 +1,65 @@
+package wav
+
+import (
+	"fmt"
+	"io"
+)
+
+// NewConvertingReader returns an io.Reader that reads the audio from src and
+// converts it to target's format, producing interleaved bytes exactly as they
+// would appear in the data chunk of a file in that format. The audio is decoded
+// and re-encoded as it is read, so it can be used with io.Copy into a Writer
+// for the target format. Only the sample format and bit depth can change:
+// target must have the same number of channels and sample rate as src.
+// Problems with the conversion are returned from the first call to Read.
+func NewConvertingReader(src *Reader, target FileFormat) io.Reader {
+	cr := &convertingReader{src: src}
+	if target.Channels != src.Channels() || target.SampleRate != src.Samplerate() {
+		cr.err = fmt.Errorf("converting %d channels at %dHz to %d channels at %dHz not supported", src.Channels(), src.Samplerate(), target.Channels, target.SampleRate)
+		return cr
+	}
+	fc, err := target.chunk()
+	if err != nil {
+		cr.err = err
+		return cr
+	}
+	cr.encode, err = float32Encoder(fc)
+	if err != nil {
+		cr.err = err
+		return cr
+	}
+	cr.buf = makeSlices[float32](src.Channels(), pipeFrames)
+	return cr
+}
+
+// convertingReader decodes blocks of audio from a Reader and re-encodes them.
+type convertingReader struct {
+	src    *Reader
+	encode func([]byte, float32) []byte
+	buf    [][]float32
+	// pending holds encoded bytes that haven't been read yet.
+	pending []byte
+	scratch []byte
+	// err is returned once pending is empty.
+	err error
+}
+
+func (cr *convertingReader) Read(p []byte) (int, error) {
+	for len(cr.pending) == 0 {
+		if cr.err != nil {
+			return 0, cr.err
+		}
+		n, err := cr.src.Read32Float(cr.buf)
+		cr.scratch = cr.scratch[:0]
+		for i := range n {
+			for c := range cr.buf {
+				cr.scratch = cr.encode(cr.scratch, cr.buf[c][i])
+			}
+		}
+		cr.pending = cr.scratch
+		cr.err = err
+	}
+	n := copy(p, cr.pending)
+	cr.pending = cr.pending[n:]
+	return n, nil
+}
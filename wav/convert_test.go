@@ -0,0 +1,102 @@
+package wav
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestConvertingReader(t *testing.T) {
+	// More than one block, so the reader has to refill.
+	const n = pipeFrames + 100
+	samples := makeSlices[int16](2, n)
+	want := makeSlices[float32](2, n)
+	for i := range n {
+		samples[0][i] = int16(i * 7)
+		samples[1][i] = int16(-i * 3)
+		want[0][i] = from16PCMToFloat32(samples[0][i])
+		want[1][i] = from16PCMToFloat32(samples[1][i])
+	}
+	raw := write16PCM(t, FileFormat{
+		Format:     PCM,
+		BitDepth:   16,
+		Channels:   2,
+		SampleRate: 48000,
+	}, samples)
+	r, err := NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	target := FileFormat{
+		Format:     IEEEFloat,
+		BitDepth:   32,
+		Channels:   2,
+		SampleRate: 48000,
+	}
+	path := filepath.Join(t.TempDir(), "converted.wav")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewWriter(f, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.Copy(w, NewConvertingReader(r, target)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	converted, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cr, err := NewReader(bytes.NewReader(converted))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cr.Format() != IEEEFloat || cr.BitDepth() != 32 {
+		t.Errorf("converted file is %v bit %v, want 32 bit %v", cr.BitDepth(), cr.Format(), IEEEFloat)
+	}
+	got, err := ReadFull32Float(cr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff(got, want, cmpopts.EquateApprox(1e-6, 0)); d != "" {
+		t.Errorf("converted audio mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestConvertingReaderUnsupported(t *testing.T) {
+	raw := write16PCM(t, FileFormat{
+		Format:     PCM,
+		BitDepth:   16,
+		Channels:   2,
+		SampleRate: 48000,
+	}, makeSlices[int16](2, 10))
+	for _, target := range []FileFormat{
+		{Format: PCM, BitDepth: 16, Channels: 1, SampleRate: 48000},
+		{Format: PCM, BitDepth: 16, Channels: 2, SampleRate: 44100},
+		{Format: PCM, BitDepth: 32, Channels: 2, SampleRate: 48000},
+		{Format: ALaw, BitDepth: 8, Channels: 2, SampleRate: 48000},
+	} {
+		r, err := NewReader(bytes.NewReader(raw))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.ReadAll(NewConvertingReader(r, target)); err == nil {
+			t.Errorf("converting to %+v: expected error", target)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package wav
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+)
+
+// WriteCSV decodes the rest of the audio in r to floats and writes it to w as
+// CSV, for inspecting small test signals in a spreadsheet. There is a header
+// row naming the channels, then a row per frame with a column per channel. The
+// audio is streamed a block at a time rather than read all at once.
+func WriteCSV(w io.Writer, r *Reader) error {
+	cw := csv.NewWriter(w)
+	row := make([]string, r.Channels())
+	for c := range row {
+		row[c] = "channel " + strconv.Itoa(c+1)
+	}
+	if err := cw.Write(row); err != nil {
+		return err
+	}
+	buf := make([]float32, pipeFrames*r.Channels())
+	for {
+		// Write out any whole frames before reporting a truncated
+		// file.
+		frames, readErr := r.ReadFloat32Into(buf)
+		for i := range frames {
+			for c := range row {
+				row[c] = strconv.FormatFloat(float64(buf[i*len(row)+c]), 'g', -1, 32)
+			}
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			cw.Flush()
+			return readErr
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,35 @@
+package wav
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWriteCSV(t *testing.T) {
+	raw := write16PCM(t, FileFormat{
+		Format:     PCM,
+		BitDepth:   16,
+		Channels:   2,
+		SampleRate: 8000,
+	}, [][]int16{
+		{0, 32767, -32767},
+		{32767, -32767, 0},
+	})
+	r, err := NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	want := "channel 1,channel 2\n" +
+		"0,1\n" +
+		"1,-1\n" +
+		"-1,0\n"
+	if d := cmp.Diff(buf.String(), want); d != "" {
+		t.Errorf("WriteCSV() mismatch (-got, +want):\n%v", d)
+	}
+}
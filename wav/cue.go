@@ -0,0 +1,166 @@
+package wav
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/binary"
+	"fmt"
+	"slices"
+)
+
+// cuePoint is a marker in the audio, written to the cue chunk, with a label
+// written to the associated data list.
+type cuePoint struct {
+	// frame is the offset of the marker from the start of the audio, in
+	// samples per channel.
+	frame int
+	label string
+}
+
+// AppendTake writes samples to the file as with Write16PCM, and places a cue
+// marker with the given label at the start of them. The cue and adtl LIST
+// chunks holding the markers are written when the Writer is closed.
+func (w *Writer) AppendTake(samples [][]int16, label string) error {
+	start := w.framesWritten()
+	if _, err := w.Write16PCM(samples); err != nil {
+		return err
+	}
+	w.cues = append(w.cues, cuePoint{frame: start, label: label})
+	return nil
+}
+
+// framesWritten returns the number of frames written to w so far, including
+// any it is still holding on to.
+func (w *Writer) framesWritten() int {
+	if w.promote != nil {
+		return len(w.promote.samples[0])
+	}
+	return int((w.dataBytes + int64(w.fade.pending())) / int64(w.fmt.blockAlign))
+}
+
+// cueChunks returns the cue chunk and the adtl LIST chunk with their labels, or
+// nil if there are no cue points.
+func (w *Writer) cueChunks() []metadataChunk {
+	if len(w.cues) == 0 {
+		return nil
+	}
+	cue := binary.LittleEndian.AppendUint32(nil, uint32(len(w.cues)))
+	adtl := []byte("adtl")
+	for i, c := range w.cues {
+		id := uint32(i + 1)
+		cue = binary.LittleEndian.AppendUint32(cue, id)
+		// Position in the play order, which is just the sample
+		// offset as there is no playlist.
+		cue = binary.LittleEndian.AppendUint32(cue, uint32(c.frame))
+		cue = append(cue, "data"...)
+		// Chunk start and block start, both 0 for uncompressed audio
+		// in a data chunk.
+		cue = binary.LittleEndian.AppendUint32(cue, 0)
+		cue = binary.LittleEndian.AppendUint32(cue, 0)
+		cue = binary.LittleEndian.AppendUint32(cue, uint32(c.frame))
+
+		// The label is null terminated.
+		size := 4 + len(c.label) + 1
+		adtl = append(adtl, "labl"...)
+		adtl = binary.LittleEndian.AppendUint32(adtl, uint32(size))
+		adtl = binary.LittleEndian.AppendUint32(adtl, id)
+		adtl = append(adtl, c.label...)
+		adtl = append(adtl, 0)
+		if size%2 != 0 {
+			adtl = append(adtl, 0)
+		}
+	}
+	return []metadataChunk{{id: "cue ", data: cue}, {id: "LIST", data: adtl}}
+}
+
+// CuePoint is a marker from a cue chunk.
+type CuePoint struct {
+	// ID identifies the cue point, and links it to its label.
+	ID uint32
+	// Sample is the position of the marker, in samples per channel from
+	// the start of the audio.
+	Sample int
+	// Label is the text from the matching labl chunk in the associated
+	// data list, if there is one.
+	Label string
+}
+
+// cueSize is the size of each cue point in a cue chunk.
+const cueSize = 24
+
+// CuePoints returns the markers from the file's cue chunk, sorted by position,
+// or nil if it doesn't have one. Labels come from the LIST adtl chunk. Writers,
+// including this package's, often put these chunks after the audio data, so
+// those are searched too, as with AllMetadata.
+func (r *Reader) CuePoints() ([]CuePoint, error) {
+	all, err := r.allMetadata()
+	if err != nil {
+		return nil, err
+	}
+	var (
+		cues   []CuePoint
+		labels = make(map[uint32]string)
+	)
+	for _, mc := range all {
+		switch mc.id {
+		case "cue ":
+			var err error
+			if cues, err = parseCues(mc.data); err != nil {
+				return nil, err
+			}
+		case "LIST":
+			if adtl, ok := bytes.CutPrefix(mc.data, []byte("adtl")); ok {
+				if err := parseLabels(adtl, labels); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	for i := range cues {
+		cues[i].Label = labels[cues[i].ID]
+	}
+	slices.SortStableFunc(cues, func(a, b CuePoint) int {
+		return cmp.Compare(a.Sample, b.Sample)
+	})
+	return cues, nil
+}
+
+func parseCues(raw []byte) ([]CuePoint, error) {
+	if len(raw) < 4 {
+		return nil, fmt.Errorf("cue chunk too short: %d bytes", len(raw))
+	}
+	n := binary.LittleEndian.Uint32(raw)
+	raw = raw[4:]
+	if uint64(n)*cueSize > uint64(len(raw)) {
+		return nil, fmt.Errorf("cue chunk has %d points but only %d bytes for them", n, len(raw))
+	}
+	cues := make([]CuePoint, n)
+	for i := range cues {
+		p := raw[i*cueSize:]
+		// The play order position, data chunk ID, chunk start and block
+		// start only matter for compressed audio or wave lists. For
+		// plain audio in a data chunk the sample offset is all there
+		// is.
+		cues[i] = CuePoint{
+			ID:     binary.LittleEndian.Uint32(p),
+			Sample: int(binary.LittleEndian.Uint32(p[20:])),
+		}
+	}
+	return cues, nil
+}
+
+// parseLabels adds the text of the labl chunks in an associated data list to
+// labels, keyed by cue point ID.
+func parseLabels(adtl []byte, labels map[uint32]string) error {
+	err := walkSubchunks(adtl, func(id string, body []byte) error {
+		if id == "labl" && len(body) >= 4 {
+			text, _, _ := bytes.Cut(body[4:], []byte{0})
+			labels[binary.LittleEndian.Uint32(body)] = string(text)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("LIST adtl chunk: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package wav
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAppendTake(t *testing.T) {
+	takes := []struct {
+		label   string
+		samples [][]int16
+	}{
+		{"take 1", makeSlices[int16](2, 100)},
+		{"take two", makeSlices[int16](2, 250)},
+	}
+	path := filepath.Join(t.TempDir(), "takes.wav")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewWriter(f, FileFormat{
+		Format:     PCM,
+		BitDepth:   16,
+		Channels:   2,
+		SampleRate: 44100,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, take := range takes {
+		if err := w.AppendTake(take.samples, take.label); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := r.Samples(); got != 350 {
+		t.Errorf("Samples() = %d, want 350", got)
+	}
+	got, err := r.CuePoints()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []CuePoint{
+		{ID: 1, Sample: 0, Label: "take 1"},
+		{ID: 2, Sample: 100, Label: "take two"},
+	}
+	if d := cmp.Diff(got, want); d != "" {
+		t.Errorf("CuePoints() mismatch (-got, +want):\n%v", d)
+	}
+	// The cue chunks come after the audio, finding them shouldn't lose
+	// the place in it.
+	audio, err := ReadFull16PCM(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(audio[0]); got != 350 {
+		t.Errorf("read %d samples after CuePoints(), want 350", got)
+	}
+}
+
+func TestCuePoints(t *testing.T) {
+	cuePoint := func(id, sample uint32) []byte {
+		return cat(
+			uint32le(id),
+			uint32le(sample), // play order position
+			[]byte("data"),
+			uint32le(0), // chunk start
+			uint32le(0), // block start
+			uint32le(sample),
+		)
+	}
+	// Stored out of order, to check they come back sorted.
+	cue := cat(
+		uint32le(3),
+		cuePoint(1, 100),
+		cuePoint(3, 44100),
+		cuePoint(2, 2000),
+	)
+	labl := func(id uint32, text string) []byte {
+		body := cat(uint32le(id), []byte(text), []byte{0})
+		b := cat([]byte("labl"), uint32le(uint32(len(body))), body)
+		if len(body)%2 == 1 {
+			b = append(b, 0)
+		}
+		return b
+	}
+	adtl := cat([]byte("adtl"), labl(1, "intro"), labl(3, "chorus"))
+
+	r, err := NewReader(bytes.NewReader(writeRIFF(t, "WAVE",
+		rawChunk("fmt ", pcm16Fmt()),
+		rawChunk("cue ", cue),
+		rawChunk("LIST", adtl),
+		rawChunk("data", []byte{0, 0}),
+	)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := r.CuePoints()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []CuePoint{
+		{ID: 1, Sample: 100, Label: "intro"},
+		{ID: 2, Sample: 2000},
+		{ID: 3, Sample: 44100, Label: "chorus"},
+	}
+	if d := cmp.Diff(got, want); d != "" {
+		t.Errorf("CuePoints() mismatch (-got, +want):\n%v", d)
+	}
+
+	r, err = NewReader(bytes.NewReader(writeRIFF(t, "WAVE",
+		rawChunk("fmt ", pcm16Fmt()),
+		rawChunk("cue ", cue[:len(cue)-4]),
+		rawChunk("data", []byte{0, 0}),
+	)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := r.CuePoints(); err == nil {
+		t.Errorf("CuePoints() with a truncated cue chunk = %+v, expected error", got)
+	}
+}
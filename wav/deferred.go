@@ -0,0 +1,103 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/pfcm/audiofile/riff"
+)
+
+// WithDeferredFormat makes the Writer reserve space for the fmt chunk and only
+// write it when the Writer is closed. This lets streaming encoders start writing
+// audio before they know all of the details of the format: the FileFormat
+// passed to NewWriter is provisional, and can be replaced with SetFormat at any
+// point before Close. It can't be combined with WithPromoteOnClip.
+func WithDeferredFormat() WriterOption {
+	return func(o *writerOptions) {
+		o.deferredFormat = true
+	}
+}
+
+// deferredBytes is the size of the JUNK chunk reserved for a deferred format:
+// enough for the largest fmt chunk and a fact chunk, including their headers.
+const deferredBytes = 8 + 40 + 8 + 4
+
+// reserveFmt writes a JUNK chunk that writeDeferredFormat will replace, and
+// returns its position.
+func reserveFmt(ws io.WriteSeeker, rw *riff.Writer) (int64, error) {
+	pos, err := ws.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	if err := rw.WriteChunk(metadataChunk{id: "JUNK", data: make([]byte, deferredBytes-8)}.riffChunk()); err != nil {
+		return 0, err
+	}
+	return pos, nil
+}
+
+// SetFormat replaces the format of a Writer created with WithDeferredFormat.
+// Once audio has been written, the number of channels and the size of each
+// frame can't change, so the data already written stays whole frames.
+func (w *Writer) SetFormat(ff FileFormat) error {
+	if w.deferredOffset == 0 {
+		return errors.New("SetFormat needs a Writer created with WithDeferredFormat")
+	}
+	if w.closed {
+		return errors.New("SetFormat called after Close")
+	}
+	fc, err := ff.chunk()
+	if err != nil {
+		return err
+	}
+	if w.dc != nil && (fc.channels != w.fmt.channels || fc.blockAlign != w.fmt.blockAlign) {
+		return fmt.Errorf("can't change from %d channels of %d byte frames to %d channels of %d byte frames after writing audio", w.fmt.channels, w.fmt.blockAlign, fc.channels, fc.blockAlign)
+	}
+	if w.fade != nil && !canScale(fc) {
+		return fmt.Errorf("can't fade %d bit %v", fc.bitsPerSample, ff.Format)
+	}
+	if w.peak != nil && fc.channels != w.fmt.channels {
+		// Nothing has been written yet, so there are no peaks to
+		// keep.
+		w.peak = newPeakTracker(int(fc.channels))
+	}
+	w.fmt = fc
+	return nil
+}
+
+// writeDeferredFormat writes the fmt chunk, and a fact chunk if needed, over
+// the space reserved by a Writer created with WithDeferredFormat. Any space
+// left over stays as a JUNK chunk.
+func (w *Writer) writeDeferredFormat() error {
+	if w.deferredOffset == 0 {
+		return nil
+	}
+	var b bytes.Buffer
+	b.Write(binary.LittleEndian.AppendUint32([]byte("fmt "), uint32(fmtChunkSize(w.fmt))))
+	if err := writeFmtChunk(&b, w.fmt); err != nil {
+		return err
+	}
+	if needsFact(w.fmt) {
+		// The sample count is filled in along with any other fact
+		// chunk.
+		w.factOffset = w.deferredOffset + int64(b.Len()) + 8
+		b.Write(binary.LittleEndian.AppendUint32([]byte("fact"), 4))
+		b.Write(make([]byte, 4))
+	}
+	// The reservation is always big enough for a chunk header in whatever
+	// is left, if anything is.
+	if rest := deferredBytes - b.Len(); rest > 0 {
+		b.Write(binary.LittleEndian.AppendUint32([]byte("JUNK"), uint32(rest-8)))
+		b.Write(make([]byte, rest-8))
+	}
+	if _, err := w.ws.Seek(w.deferredOffset, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := w.ws.Write(b.Bytes()); err != nil {
+		return err
+	}
+	_, err := w.ws.Seek(0, io.SeekEnd)
+	return err
+}
@@ -0,0 +1,116 @@
+package wav
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pfcm/audiofile/internal/testio"
+)
+
+func TestDeferredFormat(t *testing.T) {
+	for _, c := range []struct {
+		name              string
+		provisional, want FileFormat
+	}{{
+		name:        "pcm",
+		provisional: FileFormat{Format: PCM, BitDepth: 16, Channels: 2, SampleRate: 44100},
+		want:        FileFormat{Format: PCM, BitDepth: 16, Channels: 2, SampleRate: 48000},
+	}, {
+		name:        "float needs fact",
+		provisional: FileFormat{Format: PCM, BitDepth: 32, Channels: 2, SampleRate: 44100},
+		want:        FileFormat{Format: IEEEFloat, BitDepth: 32, Channels: 2, SampleRate: 96000},
+	}, {
+		name:        "unchanged",
+		provisional: FileFormat{Format: PCM, BitDepth: 24, Channels: 1, SampleRate: 8000},
+		want:        FileFormat{Format: PCM, BitDepth: 24, Channels: 1, SampleRate: 8000},
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			// Raw bytes, so they mean the same thing whatever the
+			// format ends up being.
+			data := make([]byte, 1200)
+			for i := range data {
+				data[i] = byte(i)
+			}
+			path := filepath.Join(t.TempDir(), "deferred.wav")
+			f, err := os.Create(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w, err := NewWriter(f, c.provisional, WithDeferredFormat())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := w.Write(data); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.SetFormat(c.want); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if err := f.Close(); err != nil {
+				t.Fatal(err)
+			}
+			raw, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r, err := NewReader(bytes.NewReader(raw))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := FileFormat{
+				Format:     r.Format(),
+				BitDepth:   r.BitDepth(),
+				Channels:   r.Channels(),
+				SampleRate: r.Samplerate(),
+			}
+			if d := cmp.Diff(got, c.want); d != "" {
+				t.Errorf("format mismatch (-got, +want):\n%v", d)
+			}
+			wantSamples := len(data) / (c.want.Channels * c.want.BitDepth / 8)
+			if r.Samples() != wantSamples {
+				t.Errorf("Samples() = %d, want %d", r.Samples(), wantSamples)
+			}
+			if r.hasFact != needsFact(r.fmt) {
+				t.Errorf("hasFact = %v, want %v", r.hasFact, needsFact(r.fmt))
+			}
+			gotData := make([]byte, len(data)+1)
+			n, _ := r.Read(gotData)
+			if d := cmp.Diff(gotData[:n], data); d != "" {
+				t.Errorf("data mismatch (-got, +want):\n%v", d)
+			}
+		})
+	}
+}
+
+func TestSetFormatErrors(t *testing.T) {
+	ff := FileFormat{Format: PCM, BitDepth: 16, Channels: 2, SampleRate: 44100}
+	w, err := NewWriter(&testio.DiscardSeeker{}, ff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.SetFormat(ff); err == nil {
+		t.Error("SetFormat without WithDeferredFormat: expected error")
+	}
+
+	w, err = NewWriter(&testio.DiscardSeeker{}, ff, WithDeferredFormat())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write16PCM([][]int16{{1}, {2}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.SetFormat(FileFormat{Format: PCM, BitDepth: 24, Channels: 2, SampleRate: 44100}); err == nil {
+		t.Error("SetFormat changing the frame size after writing: expected error")
+	}
+
+	if _, err := NewWriter(&testio.DiscardSeeker{}, ff, WithDeferredFormat(), WithPromoteOnClip()); err == nil {
+		t.Error("NewWriter with WithDeferredFormat and WithPromoteOnClip: expected error")
+	}
+}
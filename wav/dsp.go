@@ -0,0 +1,215 @@
+package wav
+
+import (
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/pfcm/audiofile/internal/frameio"
+)
+
+// DCOffset returns the mean value of each channel in samples. Samples are
+// expected in the same layout as the Reader's float methods: one slice per
+// channel.
+func DCOffset(samples [][]float32) []float32 {
+	out := make([]float32, len(samples))
+	for c, ch := range samples {
+		if len(ch) == 0 {
+			continue
+		}
+		// Accumulate in float64, long files can have a lot of samples.
+		var sum float64
+		for _, s := range ch {
+			sum += float64(s)
+		}
+		out[c] = float32(sum / float64(len(ch)))
+	}
+	return out
+}
+
+// RemoveDC subtracts the DC offset of each channel from all of its samples, in
+// place.
+func RemoveDC(samples [][]float32) {
+	for c, offset := range DCOffset(samples) {
+		for i := range samples[c] {
+			samples[c][i] -= offset
+		}
+	}
+}
+
+// Crossfade joins a and b, overlapping the last n samples of a with the first n
+// samples of b using an equal-power fade. The result is a new buffer with
+// len(a[c])+len(b[c])-n samples in each channel c. It panics if a and b have
+// different numbers of channels, or if n is negative or longer than any of the
+// channels.
+func Crossfade(a, b [][]float32, n int) [][]float32 {
+	if len(a) != len(b) {
+		panic(fmt.Sprintf("wav: crossfade between %d and %d channels", len(a), len(b)))
+	}
+	out := make([][]float32, len(a))
+	for c := range a {
+		if n < 0 || n > len(a[c]) || n > len(b[c]) {
+			panic(fmt.Sprintf("wav: crossfade of %d samples between channels of length %d and %d", n, len(a[c]), len(b[c])))
+		}
+		ch := make([]float32, 0, len(a[c])+len(b[c])-n)
+		ch = append(ch, a[c][:len(a[c])-n]...)
+		fadeOut, fadeIn := a[c][len(a[c])-n:], b[c][:n]
+		for i := range n {
+			// Sample the middle of each step, so the fade is
+			// symmetric and never fully silences either side.
+			theta := (float64(i) + 0.5) / float64(n) * math.Pi / 2
+			ch = append(ch, fadeOut[i]*float32(math.Cos(theta))+fadeIn[i]*float32(math.Sin(theta)))
+		}
+		out[c] = append(ch, b[c][n:]...)
+	}
+	return out
+}
+
+// Correlation returns the normalised correlation between the two channels of a
+// stereo pair. It is 1 if the channels are identical (up to scaling), -1 if one
+// is an inverted copy of the other and near 0 if they are unrelated. If either
+// channel is silent the correlation is 0. It returns an error if samples doesn't
+// have exactly 2 channels of the same length.
+func Correlation(samples [][]float32) (float64, error) {
+	if len(samples) != 2 {
+		return 0, fmt.Errorf("correlation needs 2 channels, got %d", len(samples))
+	}
+	l, r := samples[0], samples[1]
+	if len(l) != len(r) {
+		return 0, fmt.Errorf("channel lengths differ: %d and %d", len(l), len(r))
+	}
+	var lr, ll, rr float64
+	for i := range l {
+		x, y := float64(l[i]), float64(r[i])
+		lr += x * y
+		ll += x * x
+		rr += y * y
+	}
+	if ll == 0 || rr == 0 {
+		return 0, nil
+	}
+	return lr / math.Sqrt(ll*rr), nil
+}
+
+// ToMidSide converts a stereo pair to mid and side channels, where mid is
+// (L+R)/2 and side is (L-R)/2. It returns an error if stereo doesn't have
+// exactly 2 channels of the same length.
+func ToMidSide(stereo [][]float32) (mid, side []float32, err error) {
+	if len(stereo) != 2 {
+		return nil, nil, fmt.Errorf("mid/side needs 2 channels, got %d", len(stereo))
+	}
+	l, r := stereo[0], stereo[1]
+	if len(l) != len(r) {
+		return nil, nil, fmt.Errorf("channel lengths differ: %d and %d", len(l), len(r))
+	}
+	mid, side = make([]float32, len(l)), make([]float32, len(l))
+	for i := range l {
+		mid[i] = (l[i] + r[i]) / 2
+		side[i] = (l[i] - r[i]) / 2
+	}
+	return mid, side, nil
+}
+
+// FromMidSide converts mid and side channels back to a stereo pair, undoing
+// ToMidSide: L is mid+side and R is mid-side. It panics if mid and side have
+// different lengths.
+func FromMidSide(mid, side []float32) [][]float32 {
+	if len(mid) != len(side) {
+		panic(fmt.Sprintf("wav: mid and side lengths differ: %d and %d", len(mid), len(side)))
+	}
+	out := makeSlices[float32](2, len(mid))
+	for i := range mid {
+		out[0][i] = mid[i] + side[i]
+		out[1][i] = mid[i] - side[i]
+	}
+	return out
+}
+
+// RemapChannels mixes in into a new buffer with outChannels channels. Output
+// channel o is the sum of the input channels listed in mapping[o], or silent if
+// the list is empty. It panics if the input channels have different lengths,
+// or if mapping doesn't have an entry for each output channel or refers to an
+// input channel that doesn't exist.
+func RemapChannels(in [][]float32, outChannels int, mapping [][]int) [][]float32 {
+	if len(mapping) != outChannels {
+		panic(fmt.Sprintf("wav: mapping for %d channels, want %d", len(mapping), outChannels))
+	}
+	var samples int
+	if len(in) > 0 {
+		samples = len(in[0])
+	}
+	for c := range in {
+		if len(in[c]) != samples {
+			panic(fmt.Sprintf("wav: input channel %d has %d samples, channel 0 has %d", c, len(in[c]), samples))
+		}
+	}
+	out := makeSlices[float32](outChannels, samples)
+	for o, inputs := range mapping {
+		for _, i := range inputs {
+			if i < 0 || i >= len(in) {
+				panic(fmt.Sprintf("wav: output channel %d maps from input channel %d, only have %d", o, i, len(in)))
+			}
+			for j, s := range in[i] {
+				out[o][j] += s
+			}
+		}
+	}
+	return out
+}
+
+// ZeroCrossings returns the number of times samples changes sign. Zero counts
+// as positive.
+func ZeroCrossings(samples []float32) int {
+	var n int
+	for i := 1; i < len(samples); i++ {
+		if (samples[i-1] < 0) != (samples[i] < 0) {
+			n++
+		}
+	}
+	return n
+}
+
+// FundamentalEstimate reads the rest of the audio and estimates its fundamental
+// frequency in Hz from how often it crosses zero, after mixing all of the
+// channels together. This is only a rough guide: it works well for simple
+// tones, but noise and strong harmonics push the estimate up.
+func (r *Reader) FundamentalEstimate() (float64, error) {
+	samples, err := frameio.ReadUntilEOF(r.Read32Float, r.Channels())
+	if err != nil {
+		return 0, err
+	}
+	channels := make([]int, len(samples))
+	for c := range channels {
+		channels[c] = c
+	}
+	mono := RemapChannels(samples, 1, [][]int{channels})[0]
+	if len(mono) < 2 {
+		return 0, fmt.Errorf("need at least 2 samples to estimate frequency, got %d", len(mono))
+	}
+	// Each cycle crosses zero twice.
+	seconds := float64(len(mono)-1) / float64(r.Samplerate())
+	return float64(ZeroCrossings(mono)) / 2 / seconds, nil
+}
+
+// IsSilent reads the audio and reports whether every sample is at or below
+// thresholdDB, in decibels relative to full scale (eg. -60). It stops reading as
+// soon as it finds a louder sample, so the rest of the audio may be left
+// unread.
+func (r *Reader) IsSilent(thresholdDB float64) (bool, error) {
+	threshold := math.Pow(10, thresholdDB/20)
+	buf := make([]float32, pipeFrames*r.Channels())
+	for {
+		frames, err := r.ReadFloat32Into(buf)
+		for _, s := range buf[:frames*r.Channels()] {
+			if math.Abs(float64(s)) > threshold {
+				return false, nil
+			}
+		}
+		if err == io.EOF {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+}
@@ -0,0 +1,313 @@
+package wav
+
+import (
+	"bytes"
+	"math"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRemoveDC(t *testing.T) {
+	const n = 1000
+	offsets := []float32{0.25, -0.1}
+	samples := makeSlices[float32](len(offsets), n)
+	for c, offset := range offsets {
+		for i := range samples[c] {
+			// A whole number of cycles, so the sine itself has no
+			// offset.
+			samples[c][i] = float32(0.5*math.Sin(2*math.Pi*10*float64(i)/n)) + offset
+		}
+	}
+
+	const tolerance = 1e-5
+	for c, got := range DCOffset(samples) {
+		if math.Abs(float64(got-offsets[c])) > tolerance {
+			t.Errorf("channel %d: DCOffset() = %v, want %v", c, got, offsets[c])
+		}
+	}
+	RemoveDC(samples)
+	for c, got := range DCOffset(samples) {
+		if math.Abs(float64(got)) > tolerance {
+			t.Errorf("channel %d: DCOffset() after RemoveDC = %v, want 0", c, got)
+		}
+	}
+}
+
+func TestCrossfade(t *testing.T) {
+	const (
+		length = 10000
+		n      = 4000
+	)
+	// Uncorrelated noise, so an equal-power fade should keep the energy
+	// constant.
+	rng := rand.New(rand.NewPCG(1, 2))
+	noise := func() [][]float32 {
+		out := makeSlices[float32](2, length)
+		for c := range out {
+			for i := range out[c] {
+				out[c][i] = rng.Float32()*2 - 1
+			}
+		}
+		return out
+	}
+	a, b := noise(), noise()
+
+	got := Crossfade(a, b, n)
+	if len(got) != 2 {
+		t.Fatalf("Crossfade returned %d channels, want 2", len(got))
+	}
+	power := func(s []float32) float64 {
+		var sum float64
+		for _, x := range s {
+			sum += float64(x) * float64(x)
+		}
+		return sum / float64(len(s))
+	}
+	for c := range got {
+		if l := len(got[c]); l != 2*length-n {
+			t.Fatalf("channel %d: got %d samples, want %d", c, l, 2*length-n)
+		}
+		if d := cmp.Diff(got[c][:length-n], a[c][:length-n]); d != "" {
+			t.Errorf("channel %d: start mismatch (-got, +want):\n%v", c, d)
+		}
+		if d := cmp.Diff(got[c][length:], b[c][n:]); d != "" {
+			t.Errorf("channel %d: end mismatch (-got, +want):\n%v", c, d)
+		}
+		want := (power(a[c][length-n:]) + power(b[c][:n])) / 2
+		if p := power(got[c][length-n : length]); math.Abs(p-want)/want > 0.05 {
+			t.Errorf("channel %d: fade region power %v, want %v", c, p, want)
+		}
+	}
+}
+
+func TestCorrelation(t *testing.T) {
+	const n = 10000
+	var (
+		rng   = rand.New(rand.NewPCG(3, 4))
+		sine  = make([]float32, n)
+		noise = make([]float32, n)
+	)
+	for i := range n {
+		sine[i] = float32(math.Sin(2 * math.Pi * 440 * float64(i) / 44100))
+		noise[i] = rng.Float32()*2 - 1
+	}
+	scale := func(s []float32, g float32) []float32 {
+		out := make([]float32, len(s))
+		for i := range s {
+			out[i] = s[i] * g
+		}
+		return out
+	}
+
+	for _, c := range []struct {
+		name    string
+		samples [][]float32
+		want    float64
+	}{{
+		name:    "in phase",
+		samples: [][]float32{sine, scale(sine, 0.5)},
+		want:    1,
+	}, {
+		name:    "anti-phase",
+		samples: [][]float32{sine, scale(sine, -1)},
+		want:    -1,
+	}, {
+		name:    "uncorrelated",
+		samples: [][]float32{sine, noise},
+		want:    0,
+	}, {
+		name:    "silent",
+		samples: [][]float32{sine, make([]float32, n)},
+		want:    0,
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := Correlation(c.samples)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if math.Abs(got-c.want) > 0.05 {
+				t.Errorf("Correlation() = %v, want %v", got, c.want)
+			}
+		})
+	}
+
+	if _, err := Correlation([][]float32{sine}); err == nil {
+		t.Error("Correlation(mono): expected error")
+	}
+}
+
+func TestRemapChannels(t *testing.T) {
+	in := [][]float32{
+		{1, 2, 3},
+		{0.5, -0.5, 0.25},
+	}
+	got := RemapChannels(in, 4, [][]int{
+		{0},
+		{1},
+		{0, 1},
+		{},
+	})
+	want := [][]float32{
+		{1, 2, 3},
+		{0.5, -0.5, 0.25},
+		{1.5, 1.5, 3.25},
+		{0, 0, 0},
+	}
+	if d := cmp.Diff(got, want); d != "" {
+		t.Errorf("RemapChannels mismatch (-got, +want):\n%v", d)
+	}
+
+	ragged := [][]float32{{1, 2, 3}, {1, 2}, {1, 2, 3, 4}}
+	for _, c := range []struct {
+		name    string
+		in      [][]float32
+		mapping [][]int
+	}{
+		{"wrong number of outputs", in, [][]int{{0}}},
+		{"input out of range", in, [][]int{{0}, {2}}},
+		{"negative input", in, [][]int{{-1}, {0}}},
+		{"shorter input", ragged, [][]int{{0}, {1}}},
+		{"longer input", ragged, [][]int{{0}, {2}}},
+		{"unused input of a different length", ragged, [][]int{{0}, {0}}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("RemapChannels(%v): expected panic", c.mapping)
+				}
+			}()
+			RemapChannels(c.in, 2, c.mapping)
+		})
+	}
+}
+
+func TestZeroCrossings(t *testing.T) {
+	for _, c := range []struct {
+		in   []float32
+		want int
+	}{
+		{nil, 0},
+		{[]float32{1}, 0},
+		{[]float32{1, 2, 3}, 0},
+		{[]float32{1, -1, 1, -1}, 3},
+		{[]float32{0, 1, 0, -1, 0}, 2},
+	} {
+		if got := ZeroCrossings(c.in); got != c.want {
+			t.Errorf("ZeroCrossings(%v) = %d, want %d", c.in, got, c.want)
+		}
+	}
+}
+
+func TestFundamentalEstimate(t *testing.T) {
+	const (
+		rate = 44100
+		freq = 440
+	)
+	samples := makeSlices[int16](2, rate)
+	for i := range samples[0] {
+		s := int16(20000 * math.Sin(2*math.Pi*freq*float64(i)/rate))
+		samples[0][i] = s
+		samples[1][i] = s / 2
+	}
+	raw := write16PCM(t, FileFormat{
+		Format:     PCM,
+		BitDepth:   16,
+		Channels:   2,
+		SampleRate: rate,
+	}, samples)
+	// It should also work on whatever is left after a partial read.
+	for _, skip := range []int{0, rate / 2} {
+		r, err := NewReader(bytes.NewReader(raw))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if skip > 0 {
+			if _, err := r.Read16PCM(makeSlices[int16](2, skip)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		got, err := r.FundamentalEstimate()
+		if err != nil {
+			t.Fatalf("FundamentalEstimate() after reading %d samples: %v", skip, err)
+		}
+		if math.Abs(got-freq)/freq > 0.02 {
+			t.Errorf("FundamentalEstimate() after reading %d samples = %v, want within 2%% of %v", skip, got, freq)
+		}
+	}
+}
+
+func TestIsSilent(t *testing.T) {
+	const n = 3 * pipeFrames
+	ff := FileFormat{
+		Format:     PCM,
+		BitDepth:   16,
+		Channels:   2,
+		SampleRate: 44100,
+	}
+	// Very quiet noise, around -80dB.
+	quiet := makeSlices[int16](2, n)
+	for c := range quiet {
+		for i := range quiet[c] {
+			quiet[c][i] = int16(i%7 - 3)
+		}
+	}
+	clicked := makeSlices[int16](2, n)
+	copy(clicked[0], quiet[0])
+	copy(clicked[1], quiet[1])
+	clicked[1][10] = 20000
+
+	r, err := NewReader(bytes.NewReader(write16PCM(t, ff, quiet)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if silent, err := r.IsSilent(-60); !silent || err != nil {
+		t.Errorf("IsSilent(-60) on quiet noise = %v, %v, want true, nil", silent, err)
+	}
+
+	r, err = NewReader(bytes.NewReader(write16PCM(t, ff, clicked)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if silent, err := r.IsSilent(-60); silent || err != nil {
+		t.Errorf("IsSilent(-60) with a click = %v, %v, want false, nil", silent, err)
+	}
+	// It should have stopped after the first block.
+	left, err := r.Read16PCM(makeSlices[int16](2, n))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := left, n-pipeFrames; got != want {
+		t.Errorf("%d samples left after IsSilent, want %d", got, want)
+	}
+}
+
+func TestMidSide(t *testing.T) {
+	// Samples at 16 bit resolution, like most real audio, so the
+	// arithmetic is exact.
+	rng := rand.New(rand.NewPCG(1, 2))
+	stereo := makeSlices[float32](2, 1000)
+	for c := range stereo {
+		for i := range stereo[c] {
+			stereo[c][i] = float32(rng.IntN(1<<16)-1<<15) / (1 << 15)
+		}
+	}
+	mid, side, err := ToMidSide(stereo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := mid[0], (stereo[0][0]+stereo[1][0])/2; got != want {
+		t.Errorf("mid[0] = %v, want %v", got, want)
+	}
+	if d := cmp.Diff(FromMidSide(mid, side), stereo); d != "" {
+		t.Errorf("mid/side round trip mismatch (-got, +want):\n%v", d)
+	}
+
+	if _, _, err := ToMidSide(stereo[:1]); err == nil {
+		t.Error("ToMidSide on mono: expected error")
+	}
+	if _, _, err := ToMidSide([][]float32{{0, 0}, {0}}); err == nil {
+		t.Error("ToMidSide on channels of different lengths: expected error")
+	}
+}
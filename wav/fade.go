@@ -0,0 +1,145 @@
+package wav
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// WithFadeIn makes the Writer fade in linearly over the first n samples written
+// to it.
+func WithFadeIn(n int) WriterOption {
+	return func(o *writerOptions) {
+		o.fadeIn = n
+	}
+}
+
+// WithFadeOut makes the Writer fade out linearly over the last n samples
+// written to it. Because the Writer doesn't know which samples are the last
+// until it is closed, it holds on to the most recent n samples, and only writes
+// them out in Close.
+func WithFadeOut(n int) WriterOption {
+	return func(o *writerOptions) {
+		o.fadeOut = n
+	}
+}
+
+// fader keeps track of fading the data written to a Writer.
+type fader struct {
+	// in and out are the lengths of the fades, in frames.
+	in, out int
+	// fadedIn is the number of frames that have had the fade in applied.
+	fadedIn int
+	// flushed is the number of bytes passed on to the data chunk.
+	flushed int
+	// tail holds bytes that can't be written yet, either because they
+	// might need to be faded out, or because they are an incomplete frame
+	// that needs to be faded in.
+	tail []byte
+}
+
+// pending returns the number of bytes the fader is holding on to. It is safe to
+// call on a nil fader.
+func (f *fader) pending() int {
+	if f == nil {
+		return 0
+	}
+	return len(f.tail)
+}
+
+// writeFaded applies fades to p and writes out as much as it can.
+func (w *Writer) writeFaded(p []byte) (int, error) {
+	var (
+		f          = w.fade
+		blockAlign = int(w.fmt.blockAlign)
+	)
+	f.tail = append(f.tail, p...)
+	// Fade in any complete frames that need it. Frame i starts at
+	// i*blockAlign bytes into the data, the tail starts at f.flushed.
+	for f.fadedIn < f.in && (f.fadedIn+1)*blockAlign <= f.flushed+len(f.tail) {
+		start := f.fadedIn*blockAlign - f.flushed
+		scaleFrame(w.fmt, f.tail[start:start+blockAlign], float64(f.fadedIn)/float64(f.in))
+		f.fadedIn++
+	}
+	// Keep back enough to fade out, and anything that hasn't been faded
+	// in yet.
+	n := len(f.tail) - f.out*blockAlign
+	if f.fadedIn < f.in {
+		n = min(n, f.fadedIn*blockAlign-f.flushed)
+	}
+	if n > 0 {
+		written, err := w.writeData(f.tail[:n])
+		f.flushed += written
+		f.tail = append(f.tail[:0], f.tail[written:]...)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// flushFade fades out whatever is left and writes it.
+func (w *Writer) flushFade() error {
+	f := w.fade
+	if f == nil || len(f.tail) == 0 {
+		return nil
+	}
+	blockAlign := int(w.fmt.blockAlign)
+	frames := len(f.tail) / blockAlign
+	for i := range frames {
+		// The number of frames after this one.
+		left := frames - 1 - i
+		if left < f.out {
+			scaleFrame(w.fmt, f.tail[i*blockAlign:(i+1)*blockAlign], float64(left)/float64(f.out))
+		}
+	}
+	_, err := w.writeData(f.tail)
+	f.tail = nil
+	return err
+}
+
+// canScale reports whether scaleFrame can handle samples in the format.
+func canScale(fc fmtChunk) bool {
+	switch fc.sampleFormat() {
+	case PCM:
+		return fc.bitsPerSample <= 32
+	case IEEEFloat:
+		return fc.bitsPerSample == 32 || fc.bitsPerSample == 64
+	}
+	return false
+}
+
+// scaleFrame multiplies each of the encoded samples in frame by gain, in place.
+func scaleFrame(fc fmtChunk, frame []byte, gain float64) {
+	format := fc.sampleFormat()
+	le := binary.LittleEndian
+	switch bd := fc.bitsPerSample; {
+	case format == IEEEFloat && bd == 32:
+		for b := frame; len(b) >= 4; b = b[4:] {
+			f := math.Float32frombits(le.Uint32(b))
+			le.PutUint32(b, math.Float32bits(float32(float64(f)*gain)))
+		}
+	case format == IEEEFloat:
+		for b := frame; len(b) >= 8; b = b[8:] {
+			f := math.Float64frombits(le.Uint64(b))
+			le.PutUint64(b, math.Float64bits(f*gain))
+		}
+	case bd <= 8:
+		for i, s := range frame {
+			frame[i] = byte((float64(s)-128)*gain + 128)
+		}
+	case bd <= 16:
+		for b := frame; len(b) >= 2; b = b[2:] {
+			le.PutUint16(b, uint16(int16(float64(int16(le.Uint16(b)))*gain)))
+		}
+	case bd <= 24:
+		for b := frame; len(b) >= 3; b = b[3:] {
+			i, _ := nextInt24(b)
+			i = int32(float64(i) * gain)
+			b[0], b[1], b[2] = byte(i), byte(i>>8), byte(i>>16)
+		}
+	default:
+		for b := frame; len(b) >= 4; b = b[4:] {
+			le.PutUint32(b, uint32(int32(float64(int32(le.Uint32(b)))*gain)))
+		}
+	}
+}
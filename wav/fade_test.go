@@ -0,0 +1,85 @@
+package wav
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFade(t *testing.T) {
+	const (
+		n     = 1000
+		fade  = 100
+		level = 16000
+	)
+	samples := makeSlices[int16](2, n)
+	for c := range samples {
+		for i := range samples[c] {
+			samples[c][i] = level
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "fade.wav")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewWriter(f, FileFormat{
+		Format:     PCM,
+		BitDepth:   16,
+		Channels:   2,
+		SampleRate: 44100,
+	}, WithFadeIn(fade), WithFadeOut(fade))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Write in uneven pieces, some shorter than the fades.
+	for start := 0; start < n; {
+		end := min(n, start+37+start/3)
+		if _, err := w.Write16PCM([][]int16{samples[0][start:end], samples[1][start:end]}); err != nil {
+			t.Fatal(err)
+		}
+		start = end
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadFull16PCM(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got[0]) != n {
+		t.Fatalf("got %d samples, want %d", len(got[0]), n)
+	}
+
+	for c := range got {
+		for i, s := range got[c] {
+			// Work out the expected gain.
+			want := level
+			if i < fade {
+				want = level * i / fade
+			}
+			if left := n - 1 - i; left < fade {
+				want = level * left / fade
+			}
+			if diff := int(s) - want; diff < -1 || diff > 1 {
+				t.Errorf("channel %d sample %d: got %d, want %d", c, i, s, want)
+			}
+		}
+		if first, last := got[c][0], got[c][n-1]; first != 0 || last != 0 {
+			t.Errorf("channel %d: first and last samples are %d and %d, want 0", c, first, last)
+		}
+	}
+}
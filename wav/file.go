@@ -0,0 +1,63 @@
+package wav
+
+import (
+	"bufio"
+	"os"
+)
+
+// ReadFile reads the whole wav file at path, returning its format and all of
+// the samples, converted to 32 bit floats.
+func ReadFile(path string) (FileFormat, [][]float32, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return FileFormat{}, nil, err
+	}
+	defer f.Close()
+	r, err := NewReader(bufio.NewReader(f))
+	if err != nil {
+		return FileFormat{}, nil, err
+	}
+	samples, err := ReadFull32Float(r)
+	if err != nil {
+		return FileFormat{}, nil, err
+	}
+	ff := FileFormat{
+		Format:     r.Format(),
+		BitDepth:   r.BitDepth(),
+		Channels:   r.Channels(),
+		SampleRate: r.Samplerate(),
+	}
+	return ff, samples, nil
+}
+
+// WriteFile writes samples to a new wav file at path, with format ff. The
+// samples are converted to the format if necessary.
+func WriteFile(path string, ff FileFormat, samples [][]int16) error {
+	return writeFile(path, ff, samples, (*Writer).Write16PCM)
+}
+
+// WriteFile32Float writes samples to a new wav file at path, with format ff.
+// The samples are converted to the format if necessary.
+func WriteFile32Float(path string, ff FileFormat, samples [][]float32) error {
+	return writeFile(path, ff, samples, (*Writer).Write32Float)
+}
+
+func writeFile[T any](path string, ff FileFormat, samples [][]T, write func(*Writer, [][]T) (int, error)) (err error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}()
+	w, err := NewWriter(f, ff)
+	if err != nil {
+		return err
+	}
+	if _, err := write(w, samples); err != nil {
+		return err
+	}
+	return w.Close()
+}
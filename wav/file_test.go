@@ -0,0 +1,91 @@
+package wav
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWriteFile(t *testing.T) {
+	ff := FileFormat{
+		Format:     PCM,
+		BitDepth:   16,
+		Channels:   2,
+		SampleRate: 8000,
+	}
+	samples := [][]int16{{1, 2, 3}, {-1, -2, -3}}
+	path := filepath.Join(t.TempDir(), "file.wav")
+	if err := WriteFile(path, ff, samples); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadFull16PCM(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff(got, samples); d != "" {
+		t.Errorf("samples mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestWriteFile32Float(t *testing.T) {
+	ff := FileFormat{
+		Format:     IEEEFloat,
+		BitDepth:   32,
+		Channels:   1,
+		SampleRate: 8000,
+	}
+	samples := [][]float32{{0.5, -0.25, 1}}
+	path := filepath.Join(t.TempDir(), "file.wav")
+	if err := WriteFile32Float(path, ff, samples); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadFull32Float(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff(got, samples); d != "" {
+		t.Errorf("samples mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	ff, samples, err := ReadFile("../testdata/kick.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := FileFormat{
+		Format:     PCM,
+		BitDepth:   16,
+		Channels:   1,
+		SampleRate: 44100,
+	}
+	if ff != want {
+		t.Errorf("format: got %+v, want %+v", ff, want)
+	}
+	if len(samples) != 1 {
+		t.Fatalf("got %d channels, want 1", len(samples))
+	}
+	// The data chunk is 25432 bytes of 16 bit samples.
+	if got := len(samples[0]); got != 25432/2 {
+		t.Errorf("got %d samples, want %d", got, 25432/2)
+	}
+}
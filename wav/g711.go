@@ -0,0 +1,60 @@
+package wav
+
+// G.711 log-PCM expansion tables, mapping each 8 bit codeword to a linear 16 bit
+// sample.
+var (
+	aLawTable  = expansionTable(aLawToLinear)
+	muLawTable = expansionTable(muLawToLinear)
+)
+
+func expansionTable(expand func(byte) int16) *[256]int16 {
+	var t [256]int16
+	for i := range t {
+		t[i] = expand(byte(i))
+	}
+	return &t
+}
+
+// aLawToLinear expands an A-law codeword. The even bits are inverted, the top
+// bit is the sign (set for positive), then there are 3 bits of segment and 4
+// bits of step within the segment.
+func aLawToLinear(a byte) int16 {
+	a ^= 0x55
+	t := int16(a&0x0f) << 4
+	switch seg := (a & 0x70) >> 4; seg {
+	case 0:
+		t += 8
+	case 1:
+		t += 0x108
+	default:
+		t += 0x108
+		t <<= seg - 1
+	}
+	if a&0x80 == 0 {
+		return -t
+	}
+	return t
+}
+
+// muLawToLinear expands a mu-law codeword. All the bits are inverted, the top
+// bit is the sign (set for negative), then there are 3 bits of exponent and 4
+// bits of mantissa.
+func muLawToLinear(u byte) int16 {
+	const bias = 0x84
+	u = ^u
+	t := (int16(u&0x0f)<<3 + bias) << ((u & 0x70) >> 4)
+	t -= bias
+	if u&0x80 != 0 {
+		return -t
+	}
+	return t
+}
+
+// logPCMDecoder returns a function decoding 8 bit log-PCM samples with the
+// given expansion table.
+func logPCMDecoder(table *[256]int16) func([]byte) (int16, []byte) {
+	return func(bs []byte) (int16, []byte) {
+		b, bs := nextByte(bs)
+		return table[b], bs
+	}
+}
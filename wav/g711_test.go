@@ -0,0 +1,215 @@
+package wav
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestExpansionTables(t *testing.T) {
+	for _, c := range []struct {
+		name  string
+		table *[256]int16
+		in    byte
+		want  int16
+	}{
+		{"A-law", aLawTable, 0xd5, 8},
+		{"A-law", aLawTable, 0x55, -8},
+		{"A-law", aLawTable, 0xaa, 32256},
+		{"A-law", aLawTable, 0x2a, -32256},
+		{"A-law", aLawTable, 0xc5, 264},
+		{"mu-law", muLawTable, 0xff, 0},
+		{"mu-law", muLawTable, 0x7f, 0},
+		{"mu-law", muLawTable, 0x80, 32124},
+		{"mu-law", muLawTable, 0x00, -32124},
+		{"mu-law", muLawTable, 0xfe, 8},
+		{"mu-law", muLawTable, 0x7e, -8},
+	} {
+		if got := c.table[c.in]; got != c.want {
+			t.Errorf("%s 0x%02x: got %d, want %d", c.name, c.in, got, c.want)
+		}
+	}
+}
+
+func TestReadLogPCM(t *testing.T) {
+	logFmt := func(f Format) []byte {
+		return cat(
+			uint16le(uint16(f)),
+			uint16le(1),
+			uint32le(8000),
+			uint32le(8000),
+			uint16le(1),
+			uint16le(8),
+			uint16le(0),
+		)
+	}
+	for _, c := range []struct {
+		name string
+		fmt  []byte
+		data []byte
+		want []int16
+	}{{
+		name: "A-law",
+		fmt:  logFmt(ALaw),
+		data: []byte{0xd5, 0x55, 0xaa, 0x2a},
+		want: []int16{8, -8, 32256, -32256},
+	}, {
+		name: "mu-law",
+		fmt:  logFmt(MuLaw),
+		data: []byte{0xff, 0xfe, 0x80, 0x00},
+		want: []int16{0, 8, 32124, -32124},
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			raw := writeRIFF(t, "WAVE", rawChunk("fmt ", c.fmt), rawChunk("data", c.data))
+			r, err := NewReader(bytes.NewReader(raw))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := ReadFull16PCM(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d := cmp.Diff(got, [][]int16{c.want}); d != "" {
+				t.Errorf("Read16PCM mismatch (-got, +want):\n%v", d)
+			}
+
+			r, err = NewReader(bytes.NewReader(raw))
+			if err != nil {
+				t.Fatal(err)
+			}
+			gotFloat, err := ReadFull32Float(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantFloat := make([]float32, len(c.want))
+			for i, s := range c.want {
+				wantFloat[i] = float32(s) / float32(maxInt16)
+			}
+			if d := cmp.Diff(gotFloat, [][]float32{wantFloat}, cmpopts.EquateApprox(1e-6, 0)); d != "" {
+				t.Errorf("Read32Float mismatch (-got, +want):\n%v", d)
+			}
+		})
+	}
+}
+
+// writeRaw writes data, which must already be in the format ff, to a new wav
+// file and returns its contents.
+func writeRaw(t *testing.T, ff FileFormat, data []byte) []byte {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "raw.wav")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewWriter(f, ff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestRawSamplesPassthrough(t *testing.T) {
+	for _, format := range []Format{MuLaw, ALaw} {
+		t.Run(format.String(), func(t *testing.T) {
+			ff := FileFormat{Format: format, BitDepth: 8, Channels: 2, SampleRate: 8000}
+			data := make([]byte, 512)
+			for i := range data {
+				data[i] = byte(i * 3)
+			}
+			orig := writeRaw(t, ff, data)
+
+			r, err := NewReader(bytes.NewReader(orig))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, gotFF, err := r.RawSamples()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d := cmp.Diff(gotFF, ff); d != "" {
+				t.Errorf("RawSamples() format mismatch (-got, +want):\n%v", d)
+			}
+			if d := cmp.Diff(got, data); d != "" {
+				t.Errorf("RawSamples() data mismatch (-got, +want):\n%v", d)
+			}
+			// Copying the bytes into a new file should give exactly
+			// the same file back.
+			if d := cmp.Diff(writeRaw(t, gotFF, got), orig); d != "" {
+				t.Errorf("copied file mismatch (-got, +want):\n%v", d)
+			}
+		})
+	}
+}
+
+func TestRawSamplesExtensible(t *testing.T) {
+	fc := cat(
+		uint16le(uint16(Extensible)),
+		uint16le(2),
+		uint32le(48000),
+		uint32le(48000*2*4),
+		uint16le(2*4),
+		uint16le(32),
+		uint16le(22),
+		// 24 valid bits, for the side speakers rather than the front.
+		uint16le(24),
+		uint32le(uint32(SideLeft|SideRight)),
+		mkSubformat(PCM),
+	)
+	data := make([]byte, 2*4*16)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	r, err := NewReader(bytes.NewReader(writeRIFF(t, "WAVE", rawChunk("fmt ", fc), rawChunk("data", data))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ff, err := r.RawSamples()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := FileFormat{
+		Format:          PCM,
+		BitDepth:        32,
+		Channels:        2,
+		SampleRate:      48000,
+		ForceExtensible: true,
+		ValidBits:       24,
+		ChannelMask:     uint32(SideLeft | SideRight),
+	}
+	if d := cmp.Diff(ff, want); d != "" {
+		t.Errorf("RawSamples() format mismatch (-got, +want):\n%v", d)
+	}
+	// Writing the bytes back out should give the same fmt chunk and data.
+	copied, err := NewReader(bytes.NewReader(writeRaw(t, ff, got)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if copied.fmt != r.fmt {
+		t.Errorf("copied fmt chunk = %+v, want %+v", copied.fmt, r.fmt)
+	}
+	gotData, err := io.ReadAll(copied)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff(gotData, data); d != "" {
+		t.Errorf("copied data mismatch (-got, +want):\n%v", d)
+	}
+}
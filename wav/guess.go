@@ -0,0 +1,76 @@
+package wav
+
+import (
+	"math"
+)
+
+// GuessRawFormat picks whichever of candidates makes b, raw audio without any
+// header, look the most like real audio, for recovering headerless dumps. Real
+// audio tends to be close to zero on average and to change smoothly from one
+// sample to the next, whereas reading it with the wrong sample format,
+// endianness or number of channels turns it into something much more like
+// noise. It's only a heuristic, and it returns the zero FileFormat if none of
+// the candidates can decode b at all.
+func GuessRawFormat(b []byte, candidates []FileFormat) FileFormat {
+	var (
+		best      FileFormat
+		bestScore = math.Inf(1)
+	)
+	for _, ff := range candidates {
+		score, ok := rawAudioScore(b, ff)
+		if ok && score < bestScore {
+			best, bestScore = ff, score
+		}
+	}
+	return best
+}
+
+// rawAudioScore decodes b as ff and returns how unlike audio it looks, lower
+// is better. It returns false if b can't be decoded as ff.
+func rawAudioScore(b []byte, ff FileFormat) (float64, bool) {
+	fc, err := ff.chunk()
+	if err != nil || fc.blockAlign == 0 {
+		return 0, false
+	}
+	next, err := (&Reader{fmt: fc}).float32Decoder()
+	if err != nil {
+		return 0, false
+	}
+	frames := len(b) / int(fc.blockAlign)
+	if frames < 2 {
+		return 0, false
+	}
+	var (
+		channels = ff.Channels
+		prev     = make([]float64, channels)
+		sum      = make([]float64, channels)
+		jumps    float64
+		bad      int
+		raw      = b
+	)
+	for i := range frames {
+		for c := range channels {
+			var s float32
+			s, raw = next(raw)
+			x := float64(s)
+			if math.IsNaN(x) || math.IsInf(x, 0) || math.Abs(x) > 1 {
+				// Not a sensible sample at all, count it and
+				// treat it as silence.
+				bad++
+				x = 0
+			}
+			if i > 0 {
+				jumps += math.Abs(x - prev[c])
+			}
+			prev[c] = x
+			sum[c] += x
+		}
+	}
+	var dc float64
+	for _, s := range sum {
+		dc += math.Abs(s / float64(frames))
+	}
+	samples := float64(frames * channels)
+	// Any nonsense samples are much worse than a jumpy signal.
+	return jumps/samples + dc/float64(channels) + 10*float64(bad)/samples, true
+}
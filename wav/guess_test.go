@@ -0,0 +1,36 @@
+package wav
+
+import (
+	"math"
+	"testing"
+)
+
+func TestGuessRawFormat(t *testing.T) {
+	// A stereo 16 bit recording of two different tones, with the channels
+	// out of phase.
+	const n = 4410
+	var raw []byte
+	for i := range n {
+		l := int16(12000 * math.Sin(2*math.Pi*220*float64(i)/44100))
+		r := int16(-9000 * math.Sin(2*math.Pi*330*float64(i)/44100))
+		raw = append(raw, uint16le(uint16(l))...)
+		raw = append(raw, uint16le(uint16(r))...)
+	}
+	want := FileFormat{Format: PCM, BitDepth: 16, Channels: 2, SampleRate: 44100}
+	candidates := []FileFormat{
+		{Format: PCM, BitDepth: 8, Channels: 1, SampleRate: 44100},
+		{Format: PCM, BitDepth: 8, Channels: 2, SampleRate: 44100},
+		{Format: PCM, BitDepth: 16, Channels: 1, SampleRate: 44100},
+		want,
+		{Format: PCM, BitDepth: 24, Channels: 2, SampleRate: 44100},
+		{Format: IEEEFloat, BitDepth: 32, Channels: 1, SampleRate: 44100},
+		{Format: IEEEFloat, BitDepth: 32, Channels: 2, SampleRate: 44100},
+		{Format: MuLaw, BitDepth: 8, Channels: 1, SampleRate: 44100},
+	}
+	if got := GuessRawFormat(raw, candidates); got != want {
+		t.Errorf("GuessRawFormat() = %+v, want %+v", got, want)
+	}
+	if got := GuessRawFormat(raw, nil); got != (FileFormat{}) {
+		t.Errorf("GuessRawFormat(no candidates) = %+v, want the zero FileFormat", got)
+	}
+}
@@ -0,0 +1,31 @@
+package wav
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// RangeHandler returns an http.Handler that serves the size bytes of the wav file
+// in r, supporting range requests. The Content-Type is set from the format in
+// the file's header, or to application/octet-stream if the header can't be
+// parsed.
+func RangeHandler(r io.ReaderAt, size int64) http.Handler {
+	contentType := "application/octet-stream"
+	if wr, err := NewReader(bufio.NewReader(io.NewSectionReader(r, 0, size))); err == nil {
+		contentType = mimeType(wr.Format())
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		// ServeContent handles the Range headers and sets Accept-Ranges.
+		http.ServeContent(w, req, "", time.Time{}, io.NewSectionReader(r, 0, size))
+	})
+}
+
+// mimeType returns the MIME type for a wav file with samples in format f. The
+// codecs parameter is the format tag, as described in RFC 2361.
+func mimeType(f Format) string {
+	return fmt.Sprintf("audio/wav; codecs=%d", uint16(f))
+}
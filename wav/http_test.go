@@ -0,0 +1,51 @@
+package wav
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRangeHandler(t *testing.T) {
+	raw, err := os.ReadFile("../testdata/kick.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(RangeHandler(bytes.NewReader(raw), int64(len(raw))))
+	defer srv.Close()
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Range", "bytes=40-99")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusPartialContent {
+		t.Errorf("status: got %v, want %v", resp.Status, http.StatusPartialContent)
+	}
+	for k, want := range map[string]string{
+		"Content-Type":  "audio/wav; codecs=1",
+		"Accept-Ranges": "bytes",
+	} {
+		if got := resp.Header.Get(k); got != want {
+			t.Errorf("%s header: got %q, want %q", k, got, want)
+		}
+	}
+	got, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff(got, raw[40:100]); d != "" {
+		t.Errorf("body mismatch (-got, +want):\n%v", d)
+	}
+}
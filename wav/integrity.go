@@ -0,0 +1,81 @@
+package wav
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+)
+
+// ErrNoIntegrityChunk is returned by Reader.VerifyIntegrity if the file has no
+// sum chunk to check against.
+var ErrNoIntegrityChunk = errors.New("wav: no integrity chunk")
+
+// ErrIntegrity is returned by Reader.VerifyIntegrity if the audio data doesn't
+// match the checksum stored in the file.
+var ErrIntegrity = errors.New("wav: audio data does not match checksum")
+
+// integrityChunkID is the identifier of the chunk holding the checksum. It
+// isn't part of any standard, so other software should ignore it.
+const integrityChunkID = "sum "
+
+// WithIntegrityChunk makes the Writer compute a CRC-32 (IEEE) of everything
+// written to the data chunk, and store it in a "sum " chunk after the audio when
+// the Writer is closed. Reader.VerifyIntegrity checks it, to detect archived
+// files that have been damaged.
+func WithIntegrityChunk() WriterOption {
+	return func(o *writerOptions) {
+		o.integrity = true
+	}
+}
+
+// integrityChunks returns the chunk holding the checksum of the data chunk, or
+// nil if there shouldn't be one.
+func (w *Writer) integrityChunks() []metadataChunk {
+	if w.sum == nil {
+		return nil
+	}
+	return []metadataChunk{{
+		id:   integrityChunkID,
+		data: binary.LittleEndian.AppendUint32(nil, w.sum.Sum32()),
+	}}
+}
+
+// VerifyIntegrity reads the rest of the audio data and checks it against the
+// checksum written by a Writer using WithIntegrityChunk. It returns
+// ErrNoIntegrityChunk if there is no checksum, and ErrIntegrity if it doesn't
+// match. It must be called before reading any audio, and afterwards there is
+// nothing left to read.
+func (r *Reader) VerifyIntegrity() error {
+	h := crc32.NewIEEE()
+	var want []byte
+	for _, mc := range r.metadata {
+		if mc.id == integrityChunkID {
+			want = mc.data
+		}
+	}
+	for c, err := range r.RawChunks() {
+		if err != nil {
+			return err
+		}
+		switch c.Identifier {
+		case "data":
+			if _, err := io.Copy(h, c.Reader); err != nil {
+				return err
+			}
+		case integrityChunkID:
+			mc, err := readMetadataChunk(c)
+			if err != nil {
+				return err
+			}
+			want = mc.data
+		}
+	}
+	if len(want) != 4 {
+		return ErrNoIntegrityChunk
+	}
+	if binary.LittleEndian.Uint32(want) != h.Sum32() {
+		return ErrIntegrity
+	}
+	return nil
+}
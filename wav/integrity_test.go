@@ -0,0 +1,101 @@
+package wav
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeWithIntegrity writes samples to a new 16 bit stereo wav file with an
+// integrity chunk, returning the raw bytes of the file.
+func writeWithIntegrity(t *testing.T, samples [][]int16) []byte {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.wav")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewWriter(f, FileFormat{
+		Format:     PCM,
+		BitDepth:   16,
+		Channels:   2,
+		SampleRate: 44100,
+	}, WithIntegrityChunk())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write16PCM(samples); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestIntegrityRoundTrip(t *testing.T) {
+	samples := makeSlices[int16](2, 1000)
+	for i := range samples[0] {
+		samples[0][i] = int16(i * 3)
+		samples[1][i] = int16(-i * 5)
+	}
+	raw := writeWithIntegrity(t, samples)
+	if !bytes.Contains(raw, []byte("sum ")) {
+		t.Fatal("no sum chunk in written file")
+	}
+	r, err := NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.VerifyIntegrity(); err != nil {
+		t.Errorf("VerifyIntegrity() = %v, want nil", err)
+	}
+}
+
+func TestIntegrityCorruption(t *testing.T) {
+	samples := makeSlices[int16](2, 1000)
+	for i := range samples[0] {
+		samples[0][i] = int16(i * 3)
+		samples[1][i] = int16(-i * 5)
+	}
+	raw := writeWithIntegrity(t, samples)
+	// Flip a bit somewhere in the middle of the audio.
+	i := bytes.Index(raw, []byte("data"))
+	if i < 0 {
+		t.Fatal("no data chunk in written file")
+	}
+	raw[i+8+1001] ^= 0x10
+
+	r, err := NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.VerifyIntegrity(); !errors.Is(err, ErrIntegrity) {
+		t.Errorf("VerifyIntegrity() = %v, want %v", err, ErrIntegrity)
+	}
+}
+
+func TestIntegrityMissing(t *testing.T) {
+	raw := write16PCM(t, FileFormat{
+		Format:     PCM,
+		BitDepth:   16,
+		Channels:   1,
+		SampleRate: 44100,
+	}, [][]int16{{1, 2, 3}})
+	r, err := NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.VerifyIntegrity(); !errors.Is(err, ErrNoIntegrityChunk) {
+		t.Errorf("VerifyIntegrity() = %v, want %v", err, ErrNoIntegrityChunk)
+	}
+}
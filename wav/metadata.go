@@ -0,0 +1,315 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"iter"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/pfcm/audiofile/riff"
+)
+
+// metadataChunk is a chunk that isn't audio or format information, stored in
+// full.
+type metadataChunk struct {
+	id   string
+	data []byte
+}
+
+func readMetadataChunk(c *riff.Chunk) (metadataChunk, error) {
+	data, err := io.ReadAll(c.Reader)
+	if err != nil {
+		return metadataChunk{}, err
+	}
+	return metadataChunk{id: c.Identifier, data: data}, nil
+}
+
+func (mc metadataChunk) riffChunk() *riff.Chunk {
+	return &riff.Chunk{
+		Identifier: mc.id,
+		Size:       int64(len(mc.data)),
+		Reader:     bytes.NewReader(mc.data),
+	}
+}
+
+// CopyMetadata copies all of the chunks in src, except the fmt, fact, data and
+// sum chunks, into dst. The chunks are copied unchanged and in order, so
+// proprietary metadata (eg. from Pro Tools) survives a round trip. They are
+// written when dst is closed, after the audio data.
+//
+// The chunks that come after the audio are found as with AllMetadata.
+func CopyMetadata(dst *Writer, src *Reader) error {
+	all, err := src.allMetadata()
+	if err != nil {
+		return err
+	}
+	for _, mc := range all {
+		if !writerOwnsChunk(mc.id) {
+			dst.metadata = append(dst.metadata, mc)
+		}
+	}
+	return nil
+}
+
+// MetadataChunk is a chunk from a wav file other than the fmt and data chunks.
+type MetadataChunk struct {
+	// ID is the 4 byte chunk identifier, eg. "LIST".
+	ID string
+	// Size is the number of bytes in the chunk.
+	Size int
+	// Data is the raw contents of the chunk.
+	Data []byte
+}
+
+// AllMetadata returns an iterator over every chunk in the file other than the
+// fmt and data chunks, in order, including ones the package doesn't know
+// anything about. The chunks after the audio are read the first time they are
+// needed, by this or by methods like CuePoints. If the file can seek, the
+// position in the audio is kept, otherwise any audio that hasn't been read yet
+// is skipped.
+func (r *Reader) AllMetadata() iter.Seq2[MetadataChunk, error] {
+	return func(yield func(MetadataChunk, error) bool) {
+		all, err := r.allMetadata()
+		if err != nil {
+			yield(MetadataChunk{}, err)
+			return
+		}
+		for _, mc := range all {
+			if !yield(mc.exported(), nil) {
+				return
+			}
+		}
+	}
+}
+
+// allMetadata returns the chunks before the audio data followed by the chunks
+// after it, see trailingMetadata.
+func (r *Reader) allMetadata() ([]metadataChunk, error) {
+	trailing, err := r.trailingMetadata()
+	if err != nil {
+		return nil, err
+	}
+	if len(trailing) == 0 {
+		return r.metadata, nil
+	}
+	return append(r.metadata[:len(r.metadata):len(r.metadata)], trailing...), nil
+}
+
+// trailingMetadata returns the chunks after the audio data, reading them the
+// first time it is called. If the file can seek, the position in the audio is
+// put back afterwards, otherwise any audio that hasn't been read yet is
+// skipped.
+func (r *Reader) trailingMetadata() ([]metadataChunk, error) {
+	if r.trailingRead || r.r == nil {
+		return r.trailing, nil
+	}
+	lr, _ := r.data.(*io.LimitedReader)
+	var pos, left int64
+	if r.seeker != nil && lr != nil {
+		var err error
+		if pos, err = r.seeker.Seek(0, io.SeekCurrent); err != nil {
+			return nil, err
+		}
+		left = lr.N
+	}
+	var trailing []metadataChunk
+	for {
+		// The first call skips whatever is left of the data chunk.
+		c, err := r.r.ReadChunk()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		mc, err := readMetadataChunk(c)
+		if err != nil {
+			return nil, err
+		}
+		trailing = append(trailing, mc)
+	}
+	r.trailing, r.trailingRead = trailing, true
+	if r.seeker != nil && lr != nil {
+		if _, err := r.seeker.Seek(pos, io.SeekStart); err != nil {
+			return nil, err
+		}
+		lr.N = left
+	}
+	return trailing, nil
+}
+
+func (mc metadataChunk) exported() MetadataChunk {
+	return MetadataChunk{ID: mc.id, Size: len(mc.data), Data: mc.data}
+}
+
+// writerOwnsChunk reports whether the Writer writes chunks with the given id
+// itself.
+func writerOwnsChunk(id string) bool {
+	switch id {
+	case "fmt ", "fact", "data", integrityChunkID:
+		return true
+	}
+	return false
+}
+
+// ID3 returns the raw contents of the file's id3 chunk, which should be an ID3v2
+// tag, or nil if there isn't one. Only chunks before the audio data are
+// searched.
+func (r *Reader) ID3() ([]byte, error) {
+	for _, mc := range r.metadata {
+		switch mc.id {
+		case "id3 ", "ID3 ":
+			return mc.data, nil
+		}
+	}
+	return nil, nil
+}
+
+// AFspInfo returns the annotations from the file's afsp chunk, as written by the
+// AFsp audio tools, or nil if there isn't one. Each annotation is usually of the
+// form "name: value". Only chunks before the audio data are searched.
+func (r *Reader) AFspInfo() ([]string, error) {
+	for _, mc := range r.metadata {
+		if mc.id != "afsp" {
+			continue
+		}
+		// The chunk starts with a signature, then has null terminated
+		// strings.
+		data, ok := bytes.CutPrefix(mc.data, []byte("AFsp"))
+		if !ok {
+			return nil, fmt.Errorf("afsp chunk: bad signature %q", mc.data[:min(4, len(mc.data))])
+		}
+		var info []string
+		for s := range bytes.SplitSeq(data, []byte{0}) {
+			// Ignore empty strings, there may be padding at the end.
+			if len(s) > 0 {
+				info = append(info, string(s))
+			}
+		}
+		return info, nil
+	}
+	return nil, nil
+}
+
+// LogicRegions returns the raw contents of the ResU chunk written by Logic Pro,
+// which holds region and marker information as JSON, or nil if there isn't
+// one. Only chunks before the audio data are searched.
+func (r *Reader) LogicRegions() ([]byte, error) {
+	for _, mc := range r.metadata {
+		if mc.id == "ResU" {
+			return mc.data, nil
+		}
+	}
+	return nil, nil
+}
+
+// Info returns the tags from the file's LIST INFO chunk, keyed by their 4 byte
+// IDs (eg. "IART" for the artist), or nil if there isn't one. The values should
+// be null terminated ASCII, but plenty of tools write UTF-8, so they are
+// returned as UTF-8, up to the first null. Values that aren't valid UTF-8 are
+// assumed to be Latin-1. LIST chunks of other types are ignored. The chunks
+// after the audio data are searched too, as with AllMetadata, as that is where
+// many writers put them.
+func (r *Reader) Info() (map[string]string, error) {
+	all, err := r.allMetadata()
+	if err != nil {
+		return nil, err
+	}
+	for _, mc := range all {
+		if mc.id != "LIST" {
+			continue
+		}
+		data, ok := bytes.CutPrefix(mc.data, []byte("INFO"))
+		if !ok {
+			continue
+		}
+		info := make(map[string]string)
+		err := walkSubchunks(data, func(id string, body []byte) error {
+			info[id] = infoString(body)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("LIST INFO chunk: %w", err)
+		}
+		return info, nil
+	}
+	return nil, nil
+}
+
+// walkSubchunks calls fn with the ID and body of each of the subchunks packed
+// into b, such as those in a LIST chunk, stopping at the first error.
+func walkSubchunks(b []byte, fn func(id string, body []byte) error) error {
+	for len(b) > 0 {
+		if len(b) < 8 {
+			return fmt.Errorf("%d trailing bytes", len(b))
+		}
+		id, size := string(b[:4]), binary.LittleEndian.Uint32(b[4:8])
+		b = b[8:]
+		if int64(size) > int64(len(b)) {
+			return fmt.Errorf("%q of size %d, only %d bytes left", id, size, len(b))
+		}
+		if err := fn(id, b[:size]); err != nil {
+			return err
+		}
+		// Subchunks are padded to an even length.
+		b = b[min(int(size+size%2), len(b)):]
+	}
+	return nil
+}
+
+// infoString decodes the value of an INFO tag.
+func infoString(b []byte) string {
+	b, _, _ = bytes.Cut(b, []byte{0})
+	if utf8.Valid(b) {
+		return string(b)
+	}
+	rs := make([]rune, len(b))
+	for i, c := range b {
+		rs[i] = rune(c)
+	}
+	return string(rs)
+}
+
+var (
+	chunkDecodersMu sync.RWMutex
+	chunkDecoders   = make(map[string]func([]byte) (any, error))
+)
+
+// RegisterChunkDecoder registers a function to parse chunks with the given 4
+// byte identifier, so they can be retrieved with Reader.DecodedChunk. It is
+// intended to be called from init functions of packages that understand
+// formats this one doesn't. It panics if id is not 4 bytes long, or if a
+// decoder is already registered for it.
+func RegisterChunkDecoder(id string, fn func([]byte) (any, error)) {
+	if len(id) != 4 {
+		panic(fmt.Sprintf("wav: RegisterChunkDecoder: invalid chunk ID %q", id))
+	}
+	chunkDecodersMu.Lock()
+	defer chunkDecodersMu.Unlock()
+	if _, ok := chunkDecoders[id]; ok {
+		panic(fmt.Sprintf("wav: RegisterChunkDecoder called twice for %q", id))
+	}
+	chunkDecoders[id] = fn
+}
+
+// DecodedChunk parses the first chunk with the given identifier using the
+// decoder registered with RegisterChunkDecoder, and returns the result. It
+// returns nil if there is no such chunk, and an error if no decoder has been
+// registered for id. Only chunks before the audio data are searched.
+func (r *Reader) DecodedChunk(id string) (any, error) {
+	chunkDecodersMu.RLock()
+	fn, ok := chunkDecoders[id]
+	chunkDecodersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("wav: no decoder registered for %q chunks", id)
+	}
+	for _, mc := range r.metadata {
+		if mc.id == id {
+			return fn(mc.data)
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,454 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/pfcm/audiofile/riff"
+)
+
+func TestCopyMetadata(t *testing.T) {
+	want := []metadataChunk{
+		{"minf", []byte("pro tools things")},
+		{"elm1", []byte{1, 2, 3}},
+		{"regn", []byte("after the data")},
+	}
+	src := writeRIFF(t, "WAVE",
+		rawChunk("fmt ", pcm16Fmt()),
+		rawChunk(want[0].id, want[0].data),
+		rawChunk(want[1].id, want[1].data),
+		rawChunk("data", []byte{1, 0, 2, 0, 3, 0}),
+		rawChunk(want[2].id, want[2].data),
+	)
+
+	r, err := NewReader(bytes.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "copy.wav")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := r.EquivalentWriter(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples, err := ReadFull16PCM(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write16PCM(samples); err != nil {
+		t.Fatal(err)
+	}
+	if err := CopyMetadata(w, r); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	dst, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr, err := riff.NewReader(bytes.NewReader(dst))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []metadataChunk
+	for {
+		c, err := rr.ReadChunk()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if writerOwnsChunk(c.Identifier) {
+			continue
+		}
+		mc, err := readMetadataChunk(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, mc)
+	}
+	if d := cmp.Diff(got, want, cmp.AllowUnexported(metadataChunk{})); d != "" {
+		t.Errorf("metadata mismatch (-got, +want):\n%v", d)
+	}
+	diff(t, dst, src)
+}
+
+func TestID3(t *testing.T) {
+	// Not a real tag, but it looks like the start of one.
+	tag := []byte("ID3\x04\x00\x00\x00\x00\x00\x05APIC!")
+	for _, c := range []struct {
+		name   string
+		chunks []*riff.Chunk
+		want   []byte
+	}{{
+		name: "present",
+		chunks: []*riff.Chunk{
+			rawChunk("fmt ", pcm16Fmt()),
+			rawChunk("LIST", []byte("INFO")),
+			rawChunk("id3 ", tag),
+			rawChunk("data", []byte{0, 0}),
+		},
+		want: tag,
+	}, {
+		name: "absent",
+		chunks: []*riff.Chunk{
+			rawChunk("fmt ", pcm16Fmt()),
+			rawChunk("data", []byte{0, 0}),
+		},
+		want: nil,
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			r, err := NewReader(bytes.NewReader(writeRIFF(t, "WAVE", c.chunks...)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := r.ID3()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d := cmp.Diff(got, c.want); d != "" {
+				t.Errorf("ID3() mismatch (-got, +want):\n%v", d)
+			}
+		})
+	}
+}
+
+func TestWriteID3(t *testing.T) {
+	// Odd length, to check the padding.
+	tag := []byte("ID3\x04\x00\x00\x00\x00\x00\x04TIT2")
+	path := filepath.Join(t.TempDir(), "id3.wav")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewWriter(f, FileFormat{
+		Format:     PCM,
+		BitDepth:   16,
+		Channels:   1,
+		SampleRate: 44100,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteID3(tag); err != nil {
+		t.Fatal(err)
+	}
+	samples := [][]int16{{1, 2, 3, -4}}
+	if _, err := w.Write16PCM(samples); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteID3(tag); err == nil {
+		t.Error("WriteID3 after writing audio: expected error")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := r.ID3()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff(got, tag); d != "" {
+		t.Errorf("ID3() mismatch (-got, +want):\n%v", d)
+	}
+	gotSamples, err := ReadFull16PCM(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff(gotSamples, samples); d != "" {
+		t.Errorf("samples mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestAFspInfo(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		chunks  []*riff.Chunk
+		want    []string
+		wantErr bool
+	}{{
+		name: "present",
+		chunks: []*riff.Chunk{
+			rawChunk("fmt ", pcm16Fmt()),
+			rawChunk("afsp", []byte("AFsp"+"date: 2001-02-03 04:05:06 UTC\x00"+"program: CopyAudio\x00\x00")),
+			rawChunk("data", []byte{0, 0}),
+		},
+		want: []string{
+			"date: 2001-02-03 04:05:06 UTC",
+			"program: CopyAudio",
+		},
+	}, {
+		name: "absent",
+		chunks: []*riff.Chunk{
+			rawChunk("fmt ", pcm16Fmt()),
+			rawChunk("data", []byte{0, 0}),
+		},
+		want: nil,
+	}, {
+		name: "bad signature",
+		chunks: []*riff.Chunk{
+			rawChunk("fmt ", pcm16Fmt()),
+			rawChunk("afsp", []byte("nope\x00")),
+			rawChunk("data", []byte{0, 0}),
+		},
+		wantErr: true,
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			r, err := NewReader(bytes.NewReader(writeRIFF(t, "WAVE", c.chunks...)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := r.AFspInfo()
+			if err != nil {
+				if !c.wantErr {
+					t.Fatal(err)
+				}
+				return
+			}
+			if c.wantErr {
+				t.Fatalf("AFspInfo() = %q, expected error", got)
+			}
+			if d := cmp.Diff(got, c.want); d != "" {
+				t.Errorf("AFspInfo() mismatch (-got, +want):\n%v", d)
+			}
+		})
+	}
+}
+
+func TestInfo(t *testing.T) {
+	infoChunk := func(tags ...string) *riff.Chunk {
+		data := []byte("INFO")
+		for i := 0; i < len(tags); i += 2 {
+			data = append(data, tags[i]...)
+			data = append(data, uint32le(uint32(len(tags[i+1])))...)
+			data = append(data, tags[i+1]...)
+			if len(tags[i+1])%2 == 1 {
+				data = append(data, 0)
+			}
+		}
+		return rawChunk("LIST", data)
+	}
+	for _, c := range []struct {
+		name    string
+		chunks  []*riff.Chunk
+		want    map[string]string
+		wantErr bool
+	}{{
+		name: "utf-8",
+		chunks: []*riff.Chunk{
+			rawChunk("fmt ", pcm16Fmt()),
+			infoChunk("IART", "Bj\u00f6rk Gu\u00f0mundsd\u00f3ttir\x00", "INAM", "J\u00f3ga\x00\x00"),
+			rawChunk("data", []byte{0, 0}),
+		},
+		want: map[string]string{
+			"IART": "Björk Guðmundsdóttir",
+			"INAM": "Jóga",
+		},
+	}, {
+		name: "latin-1",
+		chunks: []*riff.Chunk{
+			rawChunk("fmt ", pcm16Fmt()),
+			infoChunk("IART", "Bj\xf6rk\x00"),
+			rawChunk("data", []byte{0, 0}),
+		},
+		want: map[string]string{"IART": "Björk"},
+	}, {
+		name: "after the data",
+		chunks: []*riff.Chunk{
+			rawChunk("fmt ", pcm16Fmt()),
+			rawChunk("data", []byte{0, 0}),
+			infoChunk("INAM", "Title\x00"),
+		},
+		want: map[string]string{"INAM": "Title"},
+	}, {
+		name: "padding and other lists",
+		chunks: []*riff.Chunk{
+			rawChunk("fmt ", pcm16Fmt()),
+			rawChunk("LIST", []byte("adtllabl\x04\x00\x00\x00oops")),
+			// The odd length values are followed by a pad byte.
+			infoChunk("INAM", "Title", "ICMT", "odd\x00\x00\x00", "ISFT", "x\x00junk after the null\x00"),
+			rawChunk("data", []byte{0, 0}),
+		},
+		want: map[string]string{
+			"INAM": "Title",
+			"ICMT": "odd",
+			"ISFT": "x",
+		},
+	}, {
+		name: "absent",
+		chunks: []*riff.Chunk{
+			rawChunk("fmt ", pcm16Fmt()),
+			rawChunk("LIST", []byte("adtl")),
+			rawChunk("data", []byte{0, 0}),
+		},
+		want: nil,
+	}, {
+		name: "truncated",
+		chunks: []*riff.Chunk{
+			rawChunk("fmt ", pcm16Fmt()),
+			rawChunk("LIST", []byte("INFOIART\x10\x00\x00\x00short")),
+			rawChunk("data", []byte{0, 0}),
+		},
+		wantErr: true,
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			r, err := NewReader(bytes.NewReader(writeRIFF(t, "WAVE", c.chunks...)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := r.Info()
+			if err != nil {
+				if !c.wantErr {
+					t.Fatal(err)
+				}
+				return
+			}
+			if c.wantErr {
+				t.Fatalf("Info() = %q, expected error", got)
+			}
+			if d := cmp.Diff(got, c.want); d != "" {
+				t.Errorf("Info() mismatch (-got, +want):\n%v", d)
+			}
+		})
+	}
+}
+
+func TestLogicRegions(t *testing.T) {
+	resu := []byte(`{"regions":[{"name":"Verse","start":0,"length":44100}]}`)
+	r, err := NewReader(bytes.NewReader(writeRIFF(t, "WAVE",
+		rawChunk("fmt ", pcm16Fmt()),
+		rawChunk("ResU", resu),
+		rawChunk("data", []byte{0, 0}),
+	)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := r.LogicRegions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff(got, resu); d != "" {
+		t.Errorf("LogicRegions() mismatch (-got, +want):\n%v", d)
+	}
+
+	r, err = NewReader(bytes.NewReader(writeRIFF(t, "WAVE",
+		rawChunk("fmt ", pcm16Fmt()),
+		rawChunk("data", []byte{0, 0}),
+	)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := r.LogicRegions(); got != nil || err != nil {
+		t.Errorf("LogicRegions() without a ResU chunk: got %q, %v, want nil, nil", got, err)
+	}
+}
+
+func TestAllMetadata(t *testing.T) {
+	raw := writeRIFF(t, "WAVE",
+		rawChunk("fmt ", pcm16Fmt()),
+		rawChunk("bext", []byte("broadcast")),
+		rawChunk("LIST", []byte("INFOjunk")),
+		rawChunk("data", []byte{1, 0, 2, 0, 3, 0}),
+		rawChunk("id3 ", []byte{'I', 'D', '3'}),
+		rawChunk("zzzz", nil),
+	)
+	r, err := NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []MetadataChunk
+	for mc, err := range r.AllMetadata() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, mc)
+	}
+	want := []MetadataChunk{
+		{ID: "bext", Size: 9, Data: []byte("broadcast")},
+		{ID: "LIST", Size: 8, Data: []byte("INFOjunk")},
+		{ID: "id3 ", Size: 3, Data: []byte{'I', 'D', '3'}},
+		{ID: "zzzz", Size: 0, Data: []byte{}},
+	}
+	if d := cmp.Diff(got, want, cmpopts.EquateEmpty()); d != "" {
+		t.Errorf("AllMetadata() mismatch (-got, +want):\n%v", d)
+	}
+}
+
+// tempo is the contents of a made up chunk holding a little-endian tempo.
+type tempo struct{ BPM int }
+
+func init() {
+	RegisterChunkDecoder("tmpo", func(b []byte) (any, error) {
+		if len(b) != 2 {
+			return nil, fmt.Errorf("tmpo chunk of %d bytes, want 2", len(b))
+		}
+		return tempo{BPM: int(binary.LittleEndian.Uint16(b))}, nil
+	})
+}
+
+func TestDecodedChunk(t *testing.T) {
+	r, err := NewReader(bytes.NewReader(writeRIFF(t, "WAVE",
+		rawChunk("fmt ", pcm16Fmt()),
+		rawChunk("tmpo", uint16le(120)),
+		rawChunk("data", []byte{0, 0}),
+	)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := r.DecodedChunk("tmpo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff(got, tempo{BPM: 120}); d != "" {
+		t.Errorf("DecodedChunk(tmpo) mismatch (-got, +want):\n%v", d)
+	}
+	if _, err := r.DecodedChunk("nope"); err == nil {
+		t.Error("DecodedChunk for an unregistered ID: expected error")
+	}
+
+	r, err = NewReader(bytes.NewReader(writeRIFF(t, "WAVE",
+		rawChunk("fmt ", pcm16Fmt()),
+		rawChunk("data", []byte{0, 0}),
+	)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := r.DecodedChunk("tmpo"); got != nil || err != nil {
+		t.Errorf("DecodedChunk(tmpo) without the chunk = %v, %v, want nil, nil", got, err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("registering tmpo twice: expected panic")
+		}
+	}()
+	RegisterChunkDecoder("tmpo", nil)
+}
@@ -0,0 +1,113 @@
+package wav
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// WithPeakChunk makes the Writer keep track of the largest sample in each
+// channel, and where it is, and write them in a PEAK chunk after the audio
+// when the Writer is closed. Some editors use this to draw waveforms without
+// reading the whole file. See also Writer.SetPeak.
+func WithPeakChunk() WriterOption {
+	return func(o *writerOptions) {
+		o.peak = true
+	}
+}
+
+// peakTracker keeps track of the peak of each channel as samples are written.
+type peakTracker struct {
+	// values and positions hold the absolute value and frame of the
+	// peak of each channel so far.
+	values    []float32
+	positions []uint32
+	// override is true if SetPeak has been called, so the peaks don't
+	// need tracking any more.
+	override bool
+	// next decodes samples. It's set the first time anything is written,
+	// because a promoting Writer might change format before then.
+	next func([]byte) (float32, []byte)
+	// frame is the number of frames seen so far, and partial holds the
+	// start of a frame that has only been partly written.
+	frame   int
+	partial []byte
+}
+
+func newPeakTracker(channels int) *peakTracker {
+	return &peakTracker{
+		values:    make([]float32, channels),
+		positions: make([]uint32, channels),
+	}
+}
+
+// trackPeaks updates the peaks with the bytes in p, which have just been
+// written to w's data chunk.
+func (w *Writer) trackPeaks(p []byte) error {
+	t := w.peak
+	if t == nil || t.override {
+		return nil
+	}
+	if t.next == nil {
+		next, err := (&Reader{fmt: w.fmt}).float32Decoder()
+		if err != nil {
+			return fmt.Errorf("tracking peaks: %w", err)
+		}
+		t.next = next
+	}
+	blockAlign := int(w.fmt.blockAlign)
+	raw := p
+	if len(t.partial) > 0 {
+		t.partial = append(t.partial, p...)
+		raw = t.partial
+	}
+	for len(raw) >= blockAlign {
+		for c := range t.values {
+			var s float32
+			s, raw = t.next(raw)
+			if a := float32(math.Abs(float64(s))); a > t.values[c] {
+				t.values[c] = a
+				t.positions[c] = uint32(t.frame)
+			}
+		}
+		t.frame++
+	}
+	t.partial = append(t.partial[:0], raw...)
+	return nil
+}
+
+// SetPeak sets the values written to the PEAK chunk, instead of tracking them
+// from the samples that are written, for when they are already known. There
+// must be a peak and a position (in samples per channel from the start of the
+// audio) for each channel. It can only be used if the Writer was created with
+// WithPeakChunk.
+func (w *Writer) SetPeak(peaks []float32, positions []uint32) error {
+	if w.peak == nil {
+		return errors.New("SetPeak needs WithPeakChunk")
+	}
+	if channels := int(w.fmt.channels); len(peaks) != channels || len(positions) != channels {
+		return fmt.Errorf("got %d peaks and %d positions, want one for each of %d channels", len(peaks), len(positions), channels)
+	}
+	w.peak.override = true
+	copy(w.peak.values, peaks)
+	copy(w.peak.positions, positions)
+	return nil
+}
+
+// peakChunks returns the PEAK chunk, or nil if there shouldn't be one.
+func (w *Writer) peakChunks() []metadataChunk {
+	t := w.peak
+	if t == nil {
+		return nil
+	}
+	// Version 1, then the time the peaks were found.
+	data := binary.LittleEndian.AppendUint32(nil, 1)
+	data = binary.LittleEndian.AppendUint32(data, uint32(time.Now().Unix()))
+	for c := range t.values {
+		data = binary.LittleEndian.AppendUint32(data, math.Float32bits(t.values[c]))
+		data = binary.LittleEndian.AppendUint32(data, t.positions[c])
+	}
+	return []metadataChunk{{id: "PEAK", data: data}}
+}
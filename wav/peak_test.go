@@ -0,0 +1,185 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/pfcm/audiofile/internal/testio"
+	"github.com/pfcm/audiofile/riff"
+)
+
+type peak struct {
+	Value    float32
+	Position uint32
+}
+
+// writePeaks writes samples to a new 16 bit file with a PEAK chunk, calling
+// setPeak before closing it if it isn't nil, and returns the peaks written.
+func writePeaks(t *testing.T, samples [][]int16, setPeak func(*Writer) error) []peak {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "peak.wav")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewWriter(f, FileFormat{
+		Format:     PCM,
+		BitDepth:   16,
+		Channels:   len(samples),
+		SampleRate: 44100,
+	}, WithPeakChunk())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write16PCM(samples); err != nil {
+		t.Fatal(err)
+	}
+	if setPeak != nil {
+		if err := setPeak(w); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return readPeaks(t, raw, len(samples))
+}
+
+// readPeaks returns the peaks from the PEAK chunk in raw, checking that there is
+// one for each channel.
+func readPeaks(t *testing.T, raw []byte, channels int) []peak {
+	t.Helper()
+	rr, err := riff.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := rr.ReadUntil("PEAK")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 8+8*channels {
+		t.Fatalf("PEAK chunk is %d bytes, want %d", len(data), 8+8*channels)
+	}
+	if v := binary.LittleEndian.Uint32(data); v != 1 {
+		t.Errorf("PEAK version %d, want 1", v)
+	}
+	var peaks []peak
+	for p := data[8:]; len(p) > 0; p = p[8:] {
+		peaks = append(peaks, peak{
+			Value:    math.Float32frombits(binary.LittleEndian.Uint32(p)),
+			Position: binary.LittleEndian.Uint32(p[4:]),
+		})
+	}
+	return peaks
+}
+
+func TestPeakChunk(t *testing.T) {
+	samples := [][]int16{
+		{0, 100, -16384, 200, 16000},
+		{5, -5, 10, 32767, -20},
+	}
+	got := writePeaks(t, samples, nil)
+	want := []peak{
+		{Value: 16384.0 / 32767, Position: 2},
+		{Value: 1, Position: 3},
+	}
+	if d := cmp.Diff(got, want, cmpopts.EquateApprox(1e-6, 0)); d != "" {
+		t.Errorf("peaks mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestPeakChunkDeferredFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "peak.wav")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewWriter(f, FileFormat{
+		Format:     PCM,
+		BitDepth:   16,
+		Channels:   1,
+		SampleRate: 44100,
+	}, WithPeakChunk(), WithDeferredFormat())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The real format has more channels than the provisional one.
+	if err := w.SetFormat(FileFormat{
+		Format:     PCM,
+		BitDepth:   16,
+		Channels:   3,
+		SampleRate: 44100,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write16PCM([][]int16{{0, 100}, {-16384, 5}, {1, 32767}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := readPeaks(t, raw, 3)
+	want := []peak{
+		{Value: 100.0 / 32767, Position: 1},
+		{Value: 16384.0 / 32767, Position: 0},
+		{Value: 1, Position: 1},
+	}
+	if d := cmp.Diff(got, want, cmpopts.EquateApprox(1e-6, 0)); d != "" {
+		t.Errorf("peaks mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestSetPeak(t *testing.T) {
+	samples := [][]int16{{0, 100, -16384}, {5, -5, 10}}
+	got := writePeaks(t, samples, func(w *Writer) error {
+		if err := w.SetPeak([]float32{0.5}, []uint32{1}); err == nil {
+			t.Error("SetPeak with 1 channel for a stereo file: expected error")
+		}
+		return w.SetPeak([]float32{0.75, 0.25}, []uint32{7, 9})
+	})
+	want := []peak{
+		{Value: 0.75, Position: 7},
+		{Value: 0.25, Position: 9},
+	}
+	if d := cmp.Diff(got, want); d != "" {
+		t.Errorf("peaks mismatch (-got, +want):\n%v", d)
+	}
+
+	w, err := NewWriter(&testio.DiscardSeeker{}, FileFormat{
+		Format:     PCM,
+		BitDepth:   16,
+		Channels:   1,
+		SampleRate: 44100,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.SetPeak([]float32{1}, []uint32{0}); err == nil {
+		t.Error("SetPeak without WithPeakChunk: expected error")
+	}
+}
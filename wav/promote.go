@@ -0,0 +1,192 @@
+package wav
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// WithPromoteOnClip makes a PCM Writer switch to writing 32 bit floats if any of
+// the samples written to it would clip. Because the format isn't known until
+// the end, all of the samples are held in memory until the Writer is closed,
+// and raw writes with Write are not allowed. The option has no effect if the
+// format is not PCM.
+func WithPromoteOnClip() WriterOption {
+	return func(o *writerOptions) {
+		o.promoteOnClip = true
+	}
+}
+
+// promoter holds samples for a Writer until it knows which format to write
+// them in.
+type promoter struct {
+	// samples holds everything written so far, per channel.
+	samples [][]float32
+	// clipped is true if any of the samples are outside [-1, 1].
+	clipped bool
+	// fmtOffset and junkOffset are the positions of the bodies of the fmt
+	// chunk and the reserved JUNK chunk.
+	fmtOffset, junkOffset int64
+}
+
+// promoteSamples converts samples to floats and holds on to them until w is
+// closed. It returns the number of bytes the samples would take up in w's
+// current format. Only float samples can clip: integer samples always fit,
+// even though the most negative ones convert to just below -1.
+func promoteSamples[T any](w *Writer, samples [][]T, toFloat func(T) float32, isFloat bool) (int, error) {
+	p := w.promote
+	for c := range samples {
+		for _, s := range samples[c] {
+			f := toFloat(s)
+			if isFloat && (f > 1 || f < -1) {
+				p.clipped = true
+			}
+			p.samples[c] = append(p.samples[c], f)
+		}
+	}
+	return len(samples[0]) * int(w.fmt.blockAlign), nil
+}
+
+// flushPromoted writes out any samples being held by a promoting Writer,
+// changing the format to 32 bit float first if any of them clipped.
+func (w *Writer) flushPromoted() error {
+	p := w.promote
+	if p == nil {
+		return nil
+	}
+	w.promote = nil
+	if p.clipped {
+		fc, err := FileFormat{
+			Format:     IEEEFloat,
+			BitDepth:   32,
+			Channels:   int(w.fmt.channels),
+			SampleRate: int(w.fmt.sampleRate),
+			// Stay Extensible so the fmt chunk doesn't need
+			// to get any bigger.
+			ForceExtensible: w.fmt.format == Extensible,
+		}.chunk()
+		if err != nil {
+			return err
+		}
+		// The new fmt chunk is exactly the same size as the reserved
+		// one, so it can just be written over the top.
+		if _, err := w.ws.Seek(p.fmtOffset, io.SeekStart); err != nil {
+			return err
+		}
+		if err := writeFmtChunk(w.ws, fc); err != nil {
+			return err
+		}
+		// Turn the JUNK chunk into a fact chunk, Close will fill in the
+		// number of samples.
+		if _, err := w.ws.Seek(p.junkOffset, io.SeekStart); err != nil {
+			return err
+		}
+		hdr := binary.LittleEndian.AppendUint32([]byte("fact"), 4)
+		if _, err := w.ws.Write(hdr); err != nil {
+			return err
+		}
+		if _, err := w.ws.Seek(0, io.SeekEnd); err != nil {
+			return err
+		}
+		// maxDataBytes accounts for all of the space now.
+		w.extraBytes -= reservedBytes(w.fmt)
+		w.fmt = fc
+		w.factOffset = p.junkOffset + 8
+	}
+	if len(p.samples[0]) == 0 {
+		return nil
+	}
+	if p.clipped {
+		_, err := w.Write32Float(p.samples)
+		return err
+	}
+	appendSample, err := unpromotedEncoder(w.fmt)
+	if err != nil {
+		return err
+	}
+	_, err = writeSamples(w, p.samples, appendSample)
+	return err
+}
+
+// unpromotedEncoder is like float32Encoder for the PCM format fc, except that
+// samples below -1 become the most negative value instead of being clamped.
+// They can only come from the most negative integer samples, which are just
+// below -1 as floats, so this gets them back exactly.
+func unpromotedEncoder(fc fmtChunk) (func([]byte, float32) []byte, error) {
+	appendSample, err := float32Encoder(fc)
+	if err != nil {
+		return nil, err
+	}
+	var lowest []byte
+	switch bd := fc.bitsPerSample; {
+	case bd <= 8:
+		lowest = []byte{0}
+	case bd <= 16:
+		lowest = binary.LittleEndian.AppendUint16(nil, 1<<15)
+	default:
+		lowest = appendInt24(nil, -1<<23)
+	}
+	return func(bs []byte, f float32) []byte {
+		if f < -1 {
+			return append(bs, lowest...)
+		}
+		return appendSample(bs, f)
+	}, nil
+}
+
+// reservedBytes returns how much extra space a promoting Writer uses before
+// the data chunk: 2 bytes to grow a plain PCM fmt chunk, and a 12 byte JUNK
+// chunk that can become a fact chunk. Extensible fmt chunks are the same size
+// whatever the subformat, so they don't need to grow.
+func reservedBytes(fc fmtChunk) int {
+	if fc.format == Extensible {
+		return 12
+	}
+	return 2 + 12
+}
+
+func newPromotingWriter(ws io.WriteSeeker, fc fmtChunk, o writerOptions) (*Writer, error) {
+	rw, reserved, err := startRIFF(ws, o)
+	if err != nil {
+		return nil, err
+	}
+	fmtOffset, err := ws.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	// Write a PCM fmt chunk big enough to be replaced by a float one. The
+	// extra cbSize field is allowed for PCM, it is just usually left off.
+	wc, err := rw.NewChunk("fmt ")
+	if err != nil {
+		return nil, err
+	}
+	if err := writeFmtChunk(wc, fc); err != nil {
+		return nil, err
+	}
+	if fc.format != Extensible {
+		// cbSize, the size of the (empty) extension.
+		if _, err := wc.Write([]byte{0, 0}); err != nil {
+			return nil, err
+		}
+	}
+	if err := wc.Close(); err != nil {
+		return nil, err
+	}
+	junkOffset, err := ws.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	if err := rw.WriteChunk(metadataChunk{id: "JUNK", data: make([]byte, 4)}.riffChunk()); err != nil {
+		return nil, err
+	}
+	return &Writer{
+		fmt:        fc,
+		ws:         ws,
+		w:          rw,
+		extraBytes: reserved + reservedBytes(fc),
+		promote: &promoter{
+			samples:    make([][]float32, fc.channels),
+			fmtOffset:  fmtOffset + 8,
+			junkOffset: junkOffset,
+		},
+	}, nil
+}
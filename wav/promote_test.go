@@ -0,0 +1,142 @@
+package wav
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestPromoteOnClip(t *testing.T) {
+	for _, c := range []struct {
+		name       string
+		bitDepth   int
+		samples    [][]float32
+		wantFormat Format
+		wantDepth  int
+	}{{
+		name:       "clipping",
+		bitDepth:   16,
+		samples:    [][]float32{{0, 0.5, 1.5, -0.25}, {-2, 0, 0.125, 1}},
+		wantFormat: IEEEFloat,
+		wantDepth:  32,
+	}, {
+		name:       "not clipping",
+		bitDepth:   16,
+		samples:    [][]float32{{0, 0.5, 1, -0.25}, {-1, 0, 0.125, 1}},
+		wantFormat: PCM,
+		wantDepth:  16,
+	}, {
+		// 24 bit PCM is written as Extensible.
+		name:       "clipping extensible",
+		bitDepth:   24,
+		samples:    [][]float32{{0, 0.5, 1.5, -0.25}, {-2, 0, 0.125, 1}},
+		wantFormat: IEEEFloat,
+		wantDepth:  32,
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "promote.wav")
+			f, err := os.Create(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w, err := NewWriter(f, FileFormat{
+				Format:     PCM,
+				BitDepth:   c.bitDepth,
+				Channels:   2,
+				SampleRate: 44100,
+			}, WithPromoteOnClip())
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Write in two parts, the first of which never clips.
+			if _, err := w.Write16PCM([][]int16{{1, 2}, {3, 4}}); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := w.Write32Float(c.samples); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if err := f.Close(); err != nil {
+				t.Fatal(err)
+			}
+			raw, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r, err := NewReader(bytes.NewReader(raw))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := r.Format(); got != c.wantFormat {
+				t.Errorf("Format() = %v, want %v", got, c.wantFormat)
+			}
+			if got := r.BitDepth(); got != c.wantDepth {
+				t.Errorf("BitDepth() = %d, want %d", got, c.wantDepth)
+			}
+			got, err := ReadFull32Float(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := [][]float32{
+				append([]float32{from16PCMToFloat32(1), from16PCMToFloat32(2)}, c.samples[0]...),
+				append([]float32{from16PCMToFloat32(3), from16PCMToFloat32(4)}, c.samples[1]...),
+			}
+			// Allow for the quantisation if it stayed as 16 bit.
+			if d := cmp.Diff(got, want, cmpopts.EquateApprox(0, 1.0/math.MaxInt16)); d != "" {
+				t.Errorf("samples mismatch (-got, +want):\n%v", d)
+			}
+		})
+	}
+}
+
+func TestPromoteOnClipFullScale16PCM(t *testing.T) {
+	want := [][]int16{{-32768, 32767, 0, -1}}
+	path := filepath.Join(t.TempDir(), "promote.wav")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w, err := NewWriter(f, FileFormat{
+		Format:     PCM,
+		BitDepth:   16,
+		Channels:   1,
+		SampleRate: 44100,
+	}, WithPromoteOnClip())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write16PCM(want); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Format() != PCM || r.BitDepth() != 16 {
+		t.Fatalf("file is %d bit %v, want 16 bit %v", r.BitDepth(), r.Format(), PCM)
+	}
+	got, err := ReadFull16PCM(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff(got, want); d != "" {
+		t.Errorf("samples mismatch (-got, +want):\n%v", d)
+	}
+}
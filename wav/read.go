@@ -9,8 +9,10 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"iter"
 	"math"
 
+	"github.com/pfcm/audiofile/internal/frameio"
 	"github.com/pfcm/audiofile/riff"
 )
 
@@ -46,10 +48,19 @@ type fmtChunk struct {
 	blockAlign         uint16 // data block size, in bytes
 	bitsPerSample      uint16
 	validBitsPerSample uint16 // optional, ignore if 0
-	channelMask        uint32 // optional, probably ignored all the time anyway
+	channelMask        uint32 // optional, see SpeakerLayout
 	subFormat          Format // optional, and probably overly simplistic
 }
 
+// sampleFormat returns the format of the samples, which is the subformat if the
+// main format is Extensible.
+func (fc fmtChunk) sampleFormat() Format {
+	if fc.format == Extensible {
+		return fc.subFormat
+	}
+	return fc.format
+}
+
 var fmtMagic = [14]byte{0x0, 0x0, 0x0, 0x0, 0x10, 0x0, 0x80, 0, 0, 0xAA, 0, 0x38, 0x9B, 0x71}
 
 func readFmtChunk(r io.Reader) (fc fmtChunk, err error) {
@@ -87,6 +98,19 @@ func readFmtChunk(r io.Reader) (fc fmtChunk, err error) {
 	// Check if the extended fields should be set.
 	switch fc.format {
 	case PCM:
+		// The chunk is usually 16 bytes, but it may have an empty
+		// extension, which should have a cbSize of 0.
+		var cb [2]byte
+		switch _, err := io.ReadFull(r, cb[:]); err {
+		case io.EOF:
+			return fc, nil
+		case nil:
+		default:
+			return fmtChunk{}, err
+		}
+		if size := binary.LittleEndian.Uint16(cb[:]); size != 0 {
+			return fmtChunk{}, fmt.Errorf("format %s, expect cbSize 0, got %d", fc.format, size)
+		}
 		return fc, nil
 	case ALaw, MuLaw:
 		// There should be two more bytes, holding a zero.
@@ -117,6 +141,9 @@ func readFmtChunk(r io.Reader) (fc fmtChunk, err error) {
 			return fmtChunk{}, fmt.Errorf("format %s, expect cbSize 22, got %d", fc.format, size)
 		}
 		fc.validBitsPerSample = get16()
+		if fc.validBitsPerSample > fc.bitsPerSample {
+			return fmtChunk{}, fmt.Errorf("format %s, %d valid bits per sample is more than %d bits per sample", fc.format, fc.validBitsPerSample, fc.bitsPerSample)
+		}
 		fc.channelMask = get32()
 		// The first 2 bytes of the subformat are the actual format.
 		fc.subFormat = Format(get16())
@@ -147,16 +174,39 @@ type Reader struct {
 	fmt fmtChunk
 	// data is a reader into the data chunk of the file.
 	data io.Reader
-	// dataBytes is the total number of bytes in the data chunk.
-	dataBytes int
+	// dataBytes is the total number of bytes in the data chunk, or -1 if
+	// it isn't known. It is an int64 because RF64 data chunks can be
+	// bigger than fits in an int on 32 bit platforms.
+	dataBytes int64
+	// metadata holds the chunks found between the fmt and data chunks.
+	metadata []metadataChunk
+	// trailing holds the chunks found after the data chunk, once
+	// trailingRead is true.
+	trailing     []metadataChunk
+	trailingRead bool
+	// fact is the number of samples per channel according to the fact
+	// chunk, if hasFact is true.
+	fact    int
+	hasFact bool
+	// warnings describe problems with the file that have been worked
+	// around.
+	warnings []string
+	// seeker, if not nil, can seek the data, which starts at dataStart.
+	seeker    io.Seeker
+	dataStart int64
 	// scratch buffer to read raw bytes into before converting.
 	scratch []byte
 }
 
 // NewReader reads validates the initial metadata of the files and returns a
 // Reader, ready to read audio frames. It can make a lot of small reads, so
-// passing in a bufio.Reader may be wise.
-func NewReader(r io.Reader) (*Reader, error) {
+// passing in a bufio.Reader may be wise. Options such as
+// WithSkipCorruptChunks change how damaged files are handled.
+func NewReader(r io.Reader, opts ...ReaderOption) (*Reader, error) {
+	var o readerOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
 	rr, err := riff.NewReader(r)
 	if err != nil {
 		return nil, err
@@ -164,56 +214,333 @@ func NewReader(r io.Reader) (*Reader, error) {
 	if rr.Form != "WAVE" {
 		return nil, fmt.Errorf("bad wav file form, expect WAVE, found: %q", rr.Form)
 	}
-	// TODO: we probably shouldn't assume the fmt chunk is always next?
-	chunk, err := rr.ReadChunk()
-	if err != nil {
-		return nil, err
-	}
-	if chunk.Identifier != "fmt " {
-		return nil, fmt.Errorf("expected fmt chunk, got %q", chunk.Identifier)
+	if rr.ByteOrder != binary.LittleEndian {
+		return nil, errors.New("big-endian (RIFX) wav files are not supported")
 	}
-	fc, err := readFmtChunk(chunk.Reader)
-	if err != nil {
-		return nil, err
+	// Find the fmt and data chunks, holding on to anything we find on the
+	// way. The fmt chunk is usually first, but doesn't have to be.
+	var (
+		fc        fmtChunk
+		haveFmt   bool
+		warnings  []string
+		data      *riff.Chunk
+		metadata  []metadataChunk
+		fact      int
+		hasFact   bool
+		seeker    io.Seeker
+		dataStart int64
+		rs        *resyncer
+	)
+	if o.skipCorrupt {
+		if rs, err = newResyncer(r, rr); err != nil {
+			return nil, err
+		}
 	}
-	// Find the data chunk.
-	var data *riff.Chunk
-	for {
+	for data == nil {
 		c, err := rr.ReadChunk()
 		if err == io.EOF {
+			if !haveFmt {
+				return nil, errors.New("finding fmt chunk: unexpected EOF")
+			}
 			return nil, errors.New("finding data chunk: unexpected EOF")
 		}
 		if err != nil {
 			return nil, err
 		}
-		if c.Identifier == "data" {
+		if rs != nil && c.Identifier != "data" {
+			// A data chunk that doesn't fit is a truncated file,
+			// which can still be read, anything else is garbage.
+			ok, err := rs.fits(c)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				warnings = append(warnings, fmt.Sprintf("skipped corrupt %q chunk of size %d", c.Identifier, c.Size))
+				if err := rs.resync(); err != nil {
+					return nil, err
+				}
+				continue
+			}
+		}
+		switch c.Identifier {
+		case "fmt ":
+			if haveFmt {
+				return nil, errors.New("found a second fmt chunk")
+			}
+			if fc, err = readFmtChunk(c.Reader); err != nil {
+				return nil, err
+			}
+			haveFmt = true
+			if fc.blockAlign == 0 {
+				// Corrupt, but it can be worked out from the
+				// rest of the format.
+				fc.blockAlign = fc.channels * ((fc.bitsPerSample + 7) / 8)
+				if fc.blockAlign == 0 {
+					return nil, fmt.Errorf("fmt chunk has no block size and %d channels of %d bits", fc.channels, fc.bitsPerSample)
+				}
+				warnings = append(warnings, fmt.Sprintf("fmt chunk has a block size of 0, using %d", fc.blockAlign))
+			}
+			continue
+		case "JUNK":
+			// Padding, possibly reserving space for RF64.
+			continue
+		case "data":
+			if !haveFmt {
+				return nil, errors.New("found data chunk before fmt chunk")
+			}
 			data = c
-			break
+			// If r can seek, remember where the audio starts
+			// so the Reader can seek too.
+			if s, ok := r.(io.Seeker); ok {
+				if pos, err := s.Seek(0, io.SeekCurrent); err == nil {
+					seeker, dataStart = s, pos
+				}
+			}
+			continue
+		}
+		mc, err := readMetadataChunk(c)
+		if err != nil && rs != nil {
+			warnings = append(warnings, fmt.Sprintf("skipped unreadable %q chunk: %v", c.Identifier, err))
+			if err := rs.resync(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
 		}
-		// TODO: deal with fact chunk here
+		if mc.id == "fact" {
+			if len(mc.data) < 4 {
+				return nil, fmt.Errorf("fact chunk too short: %d bytes", len(mc.data))
+			}
+			fact = int(binary.LittleEndian.Uint32(mc.data))
+			hasFact = true
+		}
+		if list, ok := bytes.CutPrefix(mc.data, []byte("wavl")); ok && mc.id == "LIST" {
+			if !haveFmt {
+				return nil, errors.New("found wave list before fmt chunk")
+			}
+			// A wave list holds the audio in pieces, instead of
+			// a data chunk.
+			wavl, n, err := readWavl(list, fc)
+			if err != nil {
+				return nil, err
+			}
+			data = &riff.Chunk{Identifier: "data", Size: n, Reader: wavl}
+			continue
+		}
+		metadata = append(metadata, mc)
 	}
 
+	dataBytes := data.Size
+	if data.Size == int64(unknownSize) {
+		// Streaming writers that don't know how long the audio will
+		// be use this as a placeholder, the data goes up to the end of
+		// the file.
+		dataBytes = -1
+	}
 	return &Reader{
 		r:         rr,
 		fmt:       fc,
 		data:      data.Reader,
-		dataBytes: data.Size,
+		dataBytes: dataBytes,
+		metadata:  metadata,
+		fact:      fact,
+		hasFact:   hasFact,
+		warnings:  warnings,
+		seeker:    seeker,
+		dataStart: dataStart,
+	}, nil
+}
+
+// ChainReaders returns a Reader that reads the data from each of rs in turn,
+// as if they were one continuous file. All of the readers must have exactly the
+// same format. The returned Reader takes over reading from rs, so they should
+// not be used directly afterwards.
+func ChainReaders(rs ...*Reader) (*Reader, error) {
+	if len(rs) == 0 {
+		return nil, errors.New("no readers to chain")
+	}
+	var (
+		data      []io.Reader
+		dataBytes int64
+	)
+	for i, r := range rs {
+		if r.fmt != rs[0].fmt {
+			return nil, fmt.Errorf("reader %d: format mismatch:\nwant: %+v\n got: %+v", i, rs[0].fmt, r.fmt)
+		}
+		data = append(data, r.data)
+		if dataBytes >= 0 && r.dataBytes >= 0 {
+			dataBytes += r.dataBytes
+		} else {
+			dataBytes = -1
+		}
+	}
+	return &Reader{
+		r:         rs[0].r,
+		fmt:       rs[0].fmt,
+		data:      io.MultiReader(data...),
+		dataBytes: dataBytes,
 	}, nil
 }
 
+// Channel returns a Reader that reads only channel n of r, as a mono file. The
+// channel is picked out of the data as it is read, so the returned Reader
+// shares r's position and r should not be used directly afterwards.
+func (r *Reader) Channel(n int) (*Reader, error) {
+	channels := r.Channels()
+	if n < 0 || n >= channels {
+		return nil, fmt.Errorf("channel %d out of range, file has %d channels", n, channels)
+	}
+	fc := r.fmt
+	fc.channels = 1
+	fc.blockAlign /= uint16(channels)
+	fc.dataRate /= uint32(channels)
+	// The mono file doesn't have any speaker positions.
+	fc.channelMask = 0
+	return &Reader{
+		r:   r.r,
+		fmt: fc,
+		data: &channelReader{
+			r:           r.data,
+			channel:     n,
+			blockAlign:  int(r.fmt.blockAlign),
+			sampleBytes: int(fc.blockAlign),
+		},
+		dataBytes: max(int64(r.Samples())*int64(fc.blockAlign), -1),
+		metadata:  r.metadata,
+	}, nil
+}
+
+// channelReader reads interleaved samples from r, and returns only the bytes
+// for a single channel.
+type channelReader struct {
+	r           io.Reader
+	channel     int
+	blockAlign  int // bytes per frame in r
+	sampleBytes int // bytes per sample in the channel
+
+	scratch []byte
+	// pending holds bytes for the channel that have been read but didn't
+	// fit in the caller's buffer.
+	pending []byte
+	// err is returned once pending is empty, if the file was cut off part
+	// way through a frame.
+	err error
+}
+
+func (c *channelReader) Read(p []byte) (int, error) {
+	if len(c.pending) == 0 {
+		frames := max(1, len(p)/c.sampleBytes)
+		if n := frames * c.blockAlign; cap(c.scratch) < n {
+			c.scratch = make([]byte, n)
+		}
+		raw := c.scratch[:frames*c.blockAlign]
+		if c.err != nil {
+			return 0, c.err
+		}
+		n, err := io.ReadFull(c.r, raw)
+		if n < c.blockAlign {
+			// Either the end, or not even a whole frame left.
+			return 0, err
+		}
+		if n%c.blockAlign != 0 {
+			// Return the whole frames, and then the error.
+			c.err = io.ErrUnexpectedEOF
+		}
+		// Move the samples for the channel to the front of the
+		// buffer, it's fine to overwrite what we've already moved.
+		out := raw[:0]
+		for f := 0; f+c.blockAlign <= n; f += c.blockAlign {
+			start := f + c.channel*c.sampleBytes
+			out = append(out, raw[start:start+c.sampleBytes]...)
+		}
+		c.pending = out
+	}
+	n := copy(p, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
+
+// RawChunks returns an iterator over the remaining chunks in the file, without
+// decoding them. The first chunk is the data chunk, followed by any chunks that
+// come after it. The data chunk's Reader carries on from wherever reading audio
+// left off, although its Size is always the size of the whole chunk (or -1 if
+// that isn't known, see Samples). Chunks that precede the data chunk have
+// already been consumed by NewReader. As with riff.Reader, each chunk is only
+// valid until the next iteration. The Reader should not be used to read audio
+// after calling RawChunks.
+func (r *Reader) RawChunks() iter.Seq2[*riff.Chunk, error] {
+	return func(yield func(*riff.Chunk, error) bool) {
+		data := &riff.Chunk{
+			Identifier: "data",
+			Size:       r.dataBytes,
+			Reader:     r.data,
+		}
+		if !yield(data, nil) || r.r == nil {
+			// No riff.Reader means there is nothing after the
+			// data, eg. for a Snapshot.
+			return
+		}
+		if r.trailingRead {
+			// They have already been read, eg. by Info.
+			for _, mc := range r.trailing {
+				if !yield(mc.riffChunk(), nil) {
+					return
+				}
+			}
+			return
+		}
+		for {
+			c, err := r.r.ReadChunk()
+			if err == io.EOF {
+				return
+			}
+			if !yield(c, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
 // EquivalentWriter returns a *Writer that writes to the provided WriteSeeker,
 // with the same format as r.
 func (r *Reader) EquivalentWriter(ws io.WriteSeeker) (*Writer, error) {
-	return newWriter(ws, r.fmt)
+	return newWriter(ws, r.fmt, writerOptions{})
+}
+
+// RawSamples reads the rest of the data chunk without decoding it, and returns
+// the bytes along with the format needed to understand them. Passing them to
+// the Write method of a Writer created with that format copies the audio
+// exactly, which is useful for compressed formats like mu-law and A-law that
+// would otherwise be decoded and re-encoded.
+func (r *Reader) RawSamples() ([]byte, FileFormat, error) {
+	data, err := io.ReadAll(r.data)
+	if err != nil {
+		return nil, FileFormat{}, err
+	}
+	return data, FileFormat{
+		Format:          r.Format(),
+		BitDepth:        r.BitDepth(),
+		Channels:        r.Channels(),
+		SampleRate:      r.Samplerate(),
+		ForceExtensible: r.fmt.format == Extensible,
+		ValidBits:       int(r.fmt.validBitsPerSample),
+		ChannelMask:     r.fmt.channelMask,
+	}, nil
+}
+
+// Warnings returns descriptions of any problems NewReader found with the file
+// that it was able to work around, such as a missing block size in the fmt
+// chunk.
+func (r *Reader) Warnings() []string {
+	return r.warnings
 }
 
 // Format returns the sample format of the wav file. If the main format is
 // Extensible, then this returns the subformat.
 func (r *Reader) Format() Format {
-	if r.fmt.format == Extensible {
-		return r.fmt.subFormat
-	}
-	return r.fmt.format
+	return r.fmt.sampleFormat()
 }
 
 // Samplerate returns the sample rate of the wav file.
@@ -226,14 +553,87 @@ func (r *Reader) BitDepth() int {
 	return int(r.fmt.bitsPerSample)
 }
 
+// ValidBits returns the number of bits in each sample that actually hold
+// audio. This is usually the same as BitDepth, but Extensible files can say
+// that only some of the bits are used, such as 24 bit audio in 32 bit
+// containers.
+func (r *Reader) ValidBits() int {
+	if v := int(r.fmt.validBitsPerSample); v != 0 {
+		return v
+	}
+	return r.BitDepth()
+}
+
 // Channels returns the number of channels in the audio file.
 func (r *Reader) Channels() int {
 	return int(r.fmt.channels)
 }
 
+// AudioFormat describes the basic shape of some audio. It has the same fields
+// as the Format type in github.com/go-audio/audio, so one can be converted
+// directly to the other.
+type AudioFormat struct {
+	NumChannels int
+	SampleRate  int
+}
+
+// StdFormat returns the number of channels and the sample rate of the file.
+func (r *Reader) StdFormat() AudioFormat {
+	return AudioFormat{
+		NumChannels: r.Channels(),
+		SampleRate:  r.Samplerate(),
+	}
+}
+
+// unknownSize is the data chunk size streaming writers use when they don't
+// know how much audio there will be.
+const unknownSize uint32 = math.MaxUint32
+
 // Samples returns the total number of samples per channel in the audio file.
+// For formats other than PCM, this comes from the fact chunk if there is one,
+// although it is never more than will fit in the data chunk. Otherwise it is
+// worked out from the size of the data chunk. Formats where that isn't possible
+// (such as ADPCM) are rejected by NewReader.
+//
+// Files written by streaming encoders that didn't know the length of the audio
+// up front can have a data chunk with a placeholder size of 0xFFFFFFFF. For
+// those, Samples returns -1 and the audio carries on to the end of the file, so
+// Read16PCM and friends should be called until they return io.EOF.
 func (r *Reader) Samples() int {
-	return r.dataBytes / int(r.fmt.blockAlign)
+	if r.dataBytes < 0 {
+		return -1
+	}
+	n := int(min(r.dataBytes/int64(r.fmt.blockAlign), math.MaxInt))
+	if r.hasFact && r.Format() != PCM {
+		n = min(n, r.fact)
+	}
+	return n
+}
+
+// Seek moves the Reader so the next read starts at the given sample offset
+// (per channel) from the start of the audio. It only works if the io.Reader
+// passed to NewReader is also an io.Seeker, and it can't seek past the end of
+// the audio.
+func (r *Reader) Seek(sampleOffset int) error {
+	if r.seeker == nil {
+		return errors.New("wav: Seek needs the underlying reader to be an io.Seeker")
+	}
+	if sampleOffset < 0 || (r.dataBytes >= 0 && sampleOffset > r.Samples()) {
+		return fmt.Errorf("wav: seeking to sample %d of %d", sampleOffset, r.Samples())
+	}
+	offset := int64(sampleOffset) * int64(r.fmt.blockAlign)
+	if _, err := r.seeker.Seek(r.dataStart+offset, io.SeekStart); err != nil {
+		return err
+	}
+	// The data chunk's reader needs to know how much is left, so it stops
+	// at the end of the chunk.
+	if lr, ok := r.data.(*io.LimitedReader); ok {
+		lr.N = r.dataBytes - offset
+		if r.dataBytes < 0 {
+			lr.N = int64(unknownSize) - offset
+		}
+	}
+	return nil
 }
 
 // Read reads raw, undecoded, interleaved bytes from the data chunk.
@@ -264,6 +664,12 @@ func (r *Reader) Read8PCM(data [][]byte) (int, error) {
 				i, bs := nextInt16(bs)
 				return int16ToByte(i), bs
 			}
+		case bd <= 24:
+			// 3 byte samples, signed.
+			nextSample = func(bs []byte) (byte, []byte) {
+				i, bs := nextInt24(bs)
+				return byte(i>>16 + 128), bs
+			}
 		default:
 			return 0, fmt.Errorf("bit depth %d -> byte not implemented", bd)
 		}
@@ -273,8 +679,52 @@ func (r *Reader) Read8PCM(data [][]byte) (int, error) {
 	return readInto(data, r, nextSample)
 }
 
-// Read16PCM fills the provided slices with PCM int16 data from the file.
+// Read16PCM fills the provided slices with PCM int16 data from the file. Like
+// the other Read methods, it returns the number of samples read per channel,
+// and if the file is cut off part way through a frame, the whole frames before
+// it are returned along with io.ErrUnexpectedEOF.
 func (r *Reader) Read16PCM(data [][]int16) (int, error) {
+	nextSample, err := r.int16Decoder()
+	if err != nil {
+		return 0, err
+	}
+	return readInto(data, r, nextSample)
+}
+
+// ReadBigEndian16 reads interleaved samples into dst as big-endian 16 bit PCM,
+// which is what some streaming protocols expect, so dst can be written
+// straight to a network connection. The length of dst must be a multiple of
+// the size of a 16 bit frame. It returns the number of bytes written to dst.
+func (r *Reader) ReadBigEndian16(dst []byte) (int, error) {
+	frameBytes := 2 * r.Channels()
+	if len(dst)%frameBytes != 0 {
+		return 0, fmt.Errorf("buffer of %d bytes is not a whole number of %d byte frames", len(dst), frameBytes)
+	}
+	nextSample, err := r.int16Decoder()
+	if err != nil {
+		return 0, err
+	}
+	raw, err := r.readN(len(dst) / frameBytes * int(r.fmt.blockAlign))
+	if err != nil {
+		return 0, err
+	}
+	// Only decode whole frames, but say if the file was cut off part way
+	// through one, like readInto.
+	if len(raw)%int(r.fmt.blockAlign) != 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	n := len(raw) / int(r.fmt.blockAlign) * frameBytes
+	for i := 0; i < n; i += 2 {
+		var s int16
+		s, raw = nextSample(raw)
+		binary.BigEndian.PutUint16(dst[i:], uint16(s))
+	}
+	return n, err
+}
+
+// int16Decoder returns a function that decodes a single sample from the file
+// into an int16, returning the remaining bytes.
+func (r *Reader) int16Decoder() (func([]byte) (int16, []byte), error) {
 	var nextSample func([]byte) (int16, []byte)
 	switch f := r.Format(); f {
 	case PCM:
@@ -291,8 +741,63 @@ func (r *Reader) Read16PCM(data [][]int16) (int, error) {
 		case bd <= 16:
 			// as-is
 			nextSample = nextInt16
+		case bd <= 24:
+			// Truncate the lowest byte.
+			nextSample = func(bs []byte) (int16, []byte) {
+				i, bs := nextInt24(bs)
+				return int16(i >> 8), bs
+			}
+		case bd <= 32:
+			// Take the top two bytes, which works whether or not all
+			// the bits are valid.
+			nextSample = func(bs []byte) (int16, []byte) {
+				i, bs := nextInt32(bs)
+				return int16(i >> 16), bs
+			}
+		default:
+			return nil, fmt.Errorf("bit depth %d -> int16 not implemented", bd)
+		}
+	case ALaw:
+		nextSample = logPCMDecoder(aLawTable)
+	case MuLaw:
+		nextSample = logPCMDecoder(muLawTable)
+	default:
+		return nil, fmt.Errorf("format %v -> PCM not implemented", f)
+	}
+	return nextSample, nil
+}
+
+// Read24PCM fills the provided slices with 24 bit PCM data from the file, sign
+// extended into int32s, so samples are between -8388608 and 8388607. Samples
+// with a lower bit depth are scaled up, 32 bit samples have their lowest byte
+// truncated.
+func (r *Reader) Read24PCM(data [][]int32) (int, error) {
+	var nextSample func([]byte) (int32, []byte)
+	switch f := r.Format(); f {
+	case PCM:
+		switch bd := r.BitDepth(); {
+		case bd <= 8:
+			nextSample = func(bs []byte) (int32, []byte) {
+				b, bs := nextByte(bs)
+				return from8PCMTo24PCM(b), bs
+			}
+		case bd <= 16:
+			nextSample = func(bs []byte) (int32, []byte) {
+				i, bs := nextInt16(bs)
+				return from16PCMTo24PCM(i), bs
+			}
+		case bd <= 24:
+			// as-is
+			nextSample = nextInt24
+		case bd <= 32:
+			// Take the top three bytes, which works whether or not
+			// all the bits are valid.
+			nextSample = func(bs []byte) (int32, []byte) {
+				i, bs := nextInt32(bs)
+				return i >> 8, bs
+			}
 		default:
-			return 0, fmt.Errorf("bit depth %d -> int16 not implemented", bd)
+			return 0, fmt.Errorf("bit depth %d -> int24 not implemented", bd)
 		}
 	default:
 		return 0, fmt.Errorf("format %v -> PCM not implemented", f)
@@ -300,8 +805,89 @@ func (r *Reader) Read16PCM(data [][]int16) (int, error) {
 	return readInto(data, r, nextSample)
 }
 
+// ReadFloat is the same as Read32Float, for callers that just want the audio as
+// floats and don't care about the precision.
+func (r *Reader) ReadFloat(data [][]float32) (int, error) {
+	return r.Read32Float(data)
+}
+
 // Read32Float reads some of the data into 32 bit floats.
 func (r *Reader) Read32Float(data [][]float32) (int, error) {
+	nextSample, err := r.float32Decoder()
+	if err != nil {
+		return 0, err
+	}
+	return readInto(data, r, nextSample)
+}
+
+// ReadFloat32Into reads interleaved samples into dst, converting them to 32 bit
+// floats. Unlike the other read methods, it doesn't allocate once its internal
+// buffer is big enough, so it can be used in a real-time audio thread. The
+// length of dst must be a multiple of the number of channels. It returns the
+// number of frames read, that is the number of samples per channel.
+func (r *Reader) ReadFloat32Into(dst []float32) (int, error) {
+	channels := r.Channels()
+	if len(dst)%channels != 0 {
+		return 0, fmt.Errorf("buffer of %d samples is not a whole number of %d channel frames", len(dst), channels)
+	}
+	nextSample, err := r.float32Decoder()
+	if err != nil {
+		return 0, err
+	}
+	raw, err := r.readN(len(dst) / channels * int(r.fmt.blockAlign))
+	if err != nil {
+		return 0, err
+	}
+	// Only decode whole frames, but say if the file was cut off part way
+	// through one, like readInto.
+	if len(raw)%int(r.fmt.blockAlign) != 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	frames := len(raw) / int(r.fmt.blockAlign)
+	for i := range frames * channels {
+		dst[i], raw = nextSample(raw)
+	}
+	return frames, err
+}
+
+// pipeFrames is the number of frames PipeFloat32LE decodes at a time.
+const pipeFrames = 4096
+
+// PipeFloat32LE decodes the rest of the audio and writes it to w as raw,
+// interleaved, little-endian 32 bit floats, which is handy for piping into
+// other tools (eg. ffmpeg -f f32le). It only buffers a small amount of audio
+// at a time. It returns the number of bytes written to w.
+func (r *Reader) PipeFloat32LE(w io.Writer) (int64, error) {
+	var (
+		buf   = make([]float32, pipeFrames*r.Channels())
+		out   = make([]byte, 0, 4*len(buf))
+		total int64
+	)
+	for {
+		// Write out any whole frames before reporting a truncated
+		// file.
+		frames, readErr := r.ReadFloat32Into(buf)
+		out = out[:0]
+		for _, f := range buf[:frames*r.Channels()] {
+			out = binary.LittleEndian.AppendUint32(out, math.Float32bits(f))
+		}
+		n, err := w.Write(out)
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+		if readErr == io.EOF {
+			return total, nil
+		}
+		if readErr != nil {
+			return total, readErr
+		}
+	}
+}
+
+// float32Decoder returns a function that decodes a single sample from the file
+// into a float32, returning the remaining bytes.
+func (r *Reader) float32Decoder() (func([]byte) (float32, []byte), error) {
 	var nextSample func([]byte) (float32, []byte)
 	switch f := r.Format(); f {
 	case PCM:
@@ -320,15 +906,30 @@ func (r *Reader) Read32Float(data [][]float32) (int, error) {
 				i, bs := nextInt16(bs)
 				return float32(i) * div, bs
 			}
+		case bd <= 24:
+			// 3 bytes per sample
+			const div float32 = 1.0 / float32(1<<23-1)
+			nextSample = func(bs []byte) (float32, []byte) {
+				i, bs := nextInt24(bs)
+				return float32(i) * div, bs
+			}
+		case bd <= 32:
+			// 4 bytes per sample, but maybe not all valid.
+			shift, scale := r.int32Shift()
+			div := 1 / float32(scale)
+			nextSample = func(bs []byte) (float32, []byte) {
+				i, bs := nextInt32(bs)
+				return float32(i>>shift) * div, bs
+			}
 		default:
-			return 0, fmt.Errorf("PCM bit depth %d -> float 32 not implemented", bd)
+			return nil, fmt.Errorf("PCM bit depth %d -> float 32 not implemented", bd)
 		}
 	case IEEEFloat:
-		switch bd := r.BitDepth(); {
-		case bd <= 32:
+		switch bd := r.BitDepth(); bd {
+		case 32:
 			// 4 bytes per sample
 			nextSample = nextFloat32
-		case bd <= 64:
+		case 64:
 			// 8 bytes per sample
 			nextSample = func(bs []byte) (float32, []byte) {
 				s, bs := nextFloat64(bs)
@@ -338,10 +939,21 @@ func (r *Reader) Read32Float(data [][]float32) (int, error) {
 			}
 		default:
 			// wow
-			return 0, fmt.Errorf("bit depth %d -> 32 not implemented", bd)
+			return nil, fmt.Errorf("bit depth %d -> 32 not implemented", bd)
 		}
+	case ALaw, MuLaw:
+		expand, err := r.int16Decoder()
+		if err != nil {
+			return nil, err
+		}
+		nextSample = func(bs []byte) (float32, []byte) {
+			i, bs := expand(bs)
+			return from16PCMToFloat32(i), bs
+		}
+	default:
+		return nil, fmt.Errorf("format %v -> float 32 not implemented", f)
 	}
-	return readInto(data, r, nextSample)
+	return nextSample, nil
 }
 
 // Read64Float reads some of the data into 64 bit floats.
@@ -365,55 +977,59 @@ func (r *Reader) Read64Float(data [][]float64) (int, error) {
 				i, bs := nextInt16(bs)
 				return float64(i) * div, bs
 			}
+		case bd <= 24:
+			// 3 bytes per sample
+			const div float64 = 1.0 / float64(1<<23-1)
+			nextSample = func(bs []byte) (float64, []byte) {
+				i, bs := nextInt24(bs)
+				return float64(i) * div, bs
+			}
+		case bd <= 32:
+			// 4 bytes per sample, but maybe not all valid.
+			shift, scale := r.int32Shift()
+			div := 1 / float64(scale)
+			nextSample = func(bs []byte) (float64, []byte) {
+				i, bs := nextInt32(bs)
+				return float64(i>>shift) * div, bs
+			}
 		default:
 			return 0, fmt.Errorf("PCM bit depth %d -> float 32 not implemented", bd)
 		}
 	case IEEEFloat:
-		switch bd := r.BitDepth(); {
-		case bd <= 32:
+		switch bd := r.BitDepth(); bd {
+		case 32:
 			// 4 bytes per sample
 			nextSample = func(bs []byte) (float64, []byte) {
 				s, bs := nextFloat32(bs)
 				return float64(s), bs
 			}
-		case bd <= 64:
+		case 64:
 			// 8 bytes per sample
 			nextSample = nextFloat64
 		default:
 			// wow
 			return 0, fmt.Errorf("bit depth %d -> 64 not implemented", bd)
 		}
+	case ALaw, MuLaw:
+		expand, err := r.int16Decoder()
+		if err != nil {
+			return 0, err
+		}
+		nextSample = func(bs []byte) (float64, []byte) {
+			i, bs := expand(bs)
+			return from16PCMToFloat64(i), bs
+		}
+	default:
+		return 0, fmt.Errorf("format %v -> float 64 not implemented", f)
 	}
 	return readInto(data, r, nextSample)
 }
 
+// readInto reads enough audio to fill data, decoding each sample with next, and
+// returns the number of frames read. If the file ends part way through a frame,
+// the whole frames before it are returned along with io.ErrUnexpectedEOF.
 func readInto[T any](data [][]T, r *Reader, next func([]byte) (T, []byte)) (int, error) {
-	if len(data) != r.Channels() {
-		return 0, fmt.Errorf("wrong number of channels: got: %d, file has: %d", len(data), r.Channels())
-	}
-	nSamples := len(data[0])
-	// Number of bytes to read to get nSamples.
-	nBytes := nSamples * int(r.fmt.blockAlign)
-	raw, err := r.readN(nBytes)
-	if err != nil {
-		return 0, err
-	}
-	// decode and de-interleave
-	// TODO: handle partial reads better, make sure we've at least got the same
-	// amount of samples for each channel, etc.
-	readSamples := 0
-	for j := 0; j < len(data[0]) && len(raw) > 0; j++ {
-		for c := range data {
-			// TODO: could panic, should check and ErrUnexpectedEOF?
-			data[c][j], raw = next(raw)
-		}
-		readSamples++
-	}
-	if len(raw) != 0 {
-		// TODO: not zero
-		return 0, fmt.Errorf("internal error: could not use all the bytes: %d/%d left", len(raw), nBytes)
-	}
-	return readSamples, nil
+	return frameio.ReadInto(data, r.Channels(), int(r.fmt.blockAlign), r.readN, next)
 }
 
 // readN reads a certain number of bytes into the scratch buffer and returns it.
@@ -423,12 +1039,36 @@ func (r *Reader) readN(n int) ([]byte, error) {
 	}
 	scratch := r.scratch[:n]
 	gotN, err := io.ReadFull(r, scratch)
-	if errors.Is(err, io.ErrUnexpectedEOF) {
+	if gotN > 0 && errors.Is(err, io.ErrUnexpectedEOF) {
 		err = nil // we'll return a normal EOF later
 	}
+	r.clearInvalidBits(scratch[:gotN])
 	return scratch[:gotN], err
 }
 
+// clearInvalidBits zeroes the low bits of each PCM sample in raw that the fmt
+// chunk says aren't valid. Extensible files can put samples with fewer valid
+// bits into bigger containers, aligned to the most significant bit, and the
+// rest of the bits aren't necessarily zero. The samples keep the scale of their
+// containers, except that 32 bit ones are also shifted down by int32Shift.
+func (r *Reader) clearInvalidBits(raw []byte) {
+	size := (int(r.fmt.bitsPerSample) + 7) / 8
+	invalid := size*8 - int(r.fmt.validBitsPerSample)
+	if r.Format() != PCM || r.fmt.validBitsPerSample == 0 || invalid <= 0 {
+		return
+	}
+	for i := 0; i+size <= len(raw); i += size {
+		// Little-endian, so the invalid bits are in the first bytes.
+		for j, left := i, invalid; left > 0; j, left = j+1, left-8 {
+			if left >= 8 {
+				raw[j] = 0
+			} else {
+				raw[j] &^= 1<<left - 1
+			}
+		}
+	}
+}
+
 // nextByte pulls the next byte from raw and returns raw moved along by one.
 // It will panic if raw is empty.
 func nextByte(raw []byte) (byte, []byte) {
@@ -442,6 +1082,34 @@ func nextInt16(raw []byte) (int16, []byte) {
 	return int16(binary.LittleEndian.Uint16(raw)), raw[2:]
 }
 
+// nextInt24 reads a little-endian two's complement 24 bit integer from the first
+// three bytes in raw and returns it sign extended to an int32, along with raw
+// moved along by three. It will panic if raw has <3 bytes.
+func nextInt24(raw []byte) (int32, []byte) {
+	i := int32(raw[0]) | int32(raw[1])<<8 | int32(raw[2])<<16
+	// Shift up and back down to extend the sign bit.
+	return i << 8 >> 8, raw[3:]
+}
+
+// nextInt32 reads a little-endian two's complement int32 from the first four
+// bytes in raw and returns raw moved along by four. It will panic if raw has <4
+// bytes.
+func nextInt32(raw []byte) (int32, []byte) {
+	return int32(binary.LittleEndian.Uint32(raw)), raw[4:]
+}
+
+// int32Shift returns how far 32 bit PCM samples need to be shifted to the right
+// to remove any invalid bits, and the largest value they can have after that.
+// Extensible files can put samples with fewer valid bits into 32 bit
+// containers, aligned to the most significant bit.
+func (r *Reader) int32Shift() (int, int64) {
+	valid := 32
+	if v := int(r.fmt.validBitsPerSample); v != 0 && v < valid {
+		valid = v
+	}
+	return 32 - valid, 1<<(valid-1) - 1
+}
+
 // nextFloat32 reads a little-endian IEEE-754 32 bit float from the first 4
 // bytes of raw and returns raw moved along by 4. It will panic if raw has <4
 // bytes.
@@ -466,52 +1134,176 @@ func int16ToByte(i int16) byte {
 	return byte(b + 128)
 }
 
+// SampleType is the type of the samples returned by one of the ReadFull
+// functions.
+type SampleType int
+
+const (
+	Sample8PCM       SampleType = iota // byte, from ReadFull8PCM
+	Sample16PCM                        // int16, from ReadFull16PCM
+	Sample24PCM                        // int32, from ReadFull24PCM
+	Sample32Float                      // float32, from ReadFull32Float
+	Sample64Float                      // float64, from ReadFull64Float
+	SampleComplex128                   // complex128, from ReadFullComplex128
+)
+
+// size returns the number of bytes in a single sample of type st, or 0 if st
+// isn't valid.
+func (st SampleType) size() int64 {
+	switch st {
+	case Sample8PCM:
+		return 1
+	case Sample16PCM:
+		return 2
+	case Sample24PCM, Sample32Float:
+		return 4
+	case Sample64Float:
+		return 8
+	case SampleComplex128:
+		return 16
+	}
+	return 0
+}
+
+// DecodedSize returns the number of bytes of samples the ReadFull function for
+// sampleType would return, so callers can decide whether to read the whole file
+// into memory before doing so. It returns -1 if the number of samples isn't
+// known, or sampleType isn't valid.
+func (r *Reader) DecodedSize(sampleType SampleType) int64 {
+	size := sampleType.size()
+	if size == 0 || r.Samples() < 0 {
+		return -1
+	}
+	return int64(r.Channels()) * int64(r.Samples()) * size
+}
+
 // ReadFull8PCM reads all the audio data, deinterleaving and converting to 8 bit
 // PCM if necessary.
 func ReadFull8PCM(r *Reader) ([][]byte, error) {
-	return readAll(r.Read8PCM, r.Channels(), r.Samples())
+	return frameio.ReadAll(r.Read8PCM, r.Channels(), r.Samples())
 }
 
 // ReadFull16PCM reads all the audio data, deinterleaving and converting to 16
 // bit PCM if necessary.
 func ReadFull16PCM(r *Reader) ([][]int16, error) {
-	return readAll(r.Read16PCM, r.Channels(), r.Samples())
+	return frameio.ReadAll(r.Read16PCM, r.Channels(), r.Samples())
+}
+
+// ReadFull24PCM reads all the audio data, deinterleaving and converting to 24
+// bit PCM if necessary.
+func ReadFull24PCM(r *Reader) ([][]int32, error) {
+	return frameio.ReadAll(r.Read24PCM, r.Channels(), r.Samples())
 }
 
 // ReadFull32Float reads all the audio data, deinterleaving and converting to 32
 // bit floats if necessary.
 func ReadFull32Float(r *Reader) ([][]float32, error) {
-	return readAll(r.Read32Float, r.Channels(), r.Samples())
+	return frameio.ReadAll(r.Read32Float, r.Channels(), r.Samples())
 }
 
 // ReadFull64Float reads all the audio data, deinterleaving and converting to 64
 // bit floats if necessary.
 func ReadFull64Float(r *Reader) ([][]float64, error) {
-	return readAll(r.Read64Float, r.Channels(), r.Samples())
+	return frameio.ReadAll(r.Read64Float, r.Channels(), r.Samples())
+}
+
+// ReadAny reads all the audio data into the smallest in-memory type that holds
+// it without losing anything, for callers that don't mind which. The result is
+// one of:
+//
+//   - [][]byte for PCM of up to 8 bits, as from ReadFull8PCM.
+//   - [][]int16 for PCM of 9 to 16 bits, A-law and mu-law, as from
+//     ReadFull16PCM.
+//   - [][]int32 for PCM of 17 to 24 bits, as from ReadFull24PCM.
+//   - [][]float64 for PCM of more than 24 bits, as from ReadFull64Float.
+//   - [][]float32 for 32 bit IEEE float, as from ReadFull32Float.
+//   - [][]float64 for 64 bit IEEE float, as from ReadFull64Float.
+func ReadAny(r *Reader) (any, error) {
+	switch f := r.Format(); f {
+	case PCM:
+		switch bd := r.BitDepth(); {
+		case bd <= 8:
+			return asAny(ReadFull8PCM(r))
+		case bd <= 16:
+			return asAny(ReadFull16PCM(r))
+		case bd <= 24:
+			return asAny(ReadFull24PCM(r))
+		default:
+			return asAny(ReadFull64Float(r))
+		}
+	case ALaw, MuLaw:
+		return asAny(ReadFull16PCM(r))
+	case IEEEFloat:
+		if r.BitDepth() == 32 {
+			return asAny(ReadFull32Float(r))
+		}
+		return asAny(ReadFull64Float(r))
+	default:
+		return nil, fmt.Errorf("reading %v not implemented", f)
+	}
 }
 
-func readAll[T any](read func([][]T) (int, error), channels, samples int) ([][]T, error) {
-	data := makeSlices[T](channels, samples)
-	n, err := read(data)
+// asAny returns data as an any, making sure it is nil if there is an error.
+func asAny[T any](data [][]T, err error) (any, error) {
 	if err != nil {
 		return nil, err
 	}
-	if n != samples {
-		return nil, fmt.Errorf("internal error: read %d samples, expected %d", n, samples)
-	}
 	return data, nil
 }
 
+// ReadFullComplex128 reads all the audio data as 64 bit floats, and widens it
+// to complex numbers with a zero imaginary part. This is convenient for passing
+// straight into an FFT.
+func (r *Reader) ReadFullComplex128() ([][]complex128, error) {
+	data, err := ReadFull64Float(r)
+	if err != nil {
+		return nil, err
+	}
+	out := makeSlices[complex128](len(data), len(data[0]))
+	for c := range data {
+		for i, s := range data[c] {
+			out[c][i] = complex(s, 0)
+		}
+	}
+	return out, nil
+}
+
 // makeSlices makes a slice of slices of a provided shape that shares a single
 // contiguous backing array. The returned slices should therefore never be
 // appended to.
 func makeSlices[T any](iSize, jSize int) [][]T {
-	base := make([]T, iSize*jSize)
-	var (
-		out [][]T
-	)
-	for i := range iSize {
-		out = append(out, base[i*jSize:(i+1)*jSize])
+	var fb FrameBuffer[T]
+	return fb.Reset(iSize, jSize)
+}
+
+// FrameBuffer holds audio as a slice per channel, all sharing a single backing
+// array that is reused each time the buffer is Reset. Reading into the same
+// FrameBuffer over and over, instead of making new slices for each read, avoids
+// allocating. The zero value is an empty buffer ready to use.
+type FrameBuffer[T any] struct {
+	base   []T
+	frames [][]T
+}
+
+// Reset reshapes the buffer to hold samples per channel for the given number of
+// channels, and returns the slices, ready to pass to one of the Reader's Read
+// methods. Memory is only allocated if the buffer has never been this big. The
+// previous contents are not cleared, and the slices are only valid until the
+// next call to Reset.
+func (fb *FrameBuffer[T]) Reset(channels, samples int) [][]T {
+	if n := channels * samples; cap(fb.base) < n {
+		fb.base = make([]T, n)
+	}
+	fb.frames = fb.frames[:0]
+	for c := range channels {
+		// Cap each channel so appending to it can't overwrite the
+		// next one.
+		fb.frames = append(fb.frames, fb.base[c*samples:(c+1)*samples:(c+1)*samples])
 	}
-	return out
+	return fb.frames
+}
+
+// Frames returns the slices from the most recent call to Reset.
+func (fb *FrameBuffer[T]) Frames() [][]T {
+	return fb.frames
 }
@@ -0,0 +1,42 @@
+package wav
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// WithReadback makes Close read the finished file back from tee and check that
+// its format and number of samples match what was written, returning an error
+// if they don't. tee must read the same file the Writer writes to, for example
+// the same *os.File. This is a paranoid mode for tests, to catch code that
+// uses the Writer incorrectly.
+func WithReadback(tee io.ReaderAt) WriterOption {
+	return func(o *writerOptions) {
+		o.readback = tee
+	}
+}
+
+// verifyReadback reads the finished file back, if the Writer was created with
+// WithReadback.
+func (w *Writer) verifyReadback() error {
+	if w.readback == nil {
+		return nil
+	}
+	size, err := w.ws.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	r, err := NewReader(io.NewSectionReader(w.readback, 0, size))
+	if err != nil {
+		return fmt.Errorf("readback: %w", err)
+	}
+	var errs []error
+	if r.fmt != w.fmt {
+		errs = append(errs, fmt.Errorf("readback: format %+v, wrote %+v", r.fmt, w.fmt))
+	}
+	if want := int(w.dataBytes / int64(w.fmt.blockAlign)); r.Samples() != want {
+		errs = append(errs, fmt.Errorf("readback: %d samples, wrote %d", r.Samples(), want))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,58 @@
+package wav
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pfcm/audiofile/internal/testio"
+)
+
+func TestReadback(t *testing.T) {
+	ff := FileFormat{Format: PCM, BitDepth: 16, Channels: 1, SampleRate: 44100}
+	samples := [][]int16{{1, 2, 3, 4, 5, 6, 7, 8}}
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "readback.wav"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w, err := NewWriter(f, ff, WithReadback(f))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write16PCM(samples); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Errorf("Close with a matching readback: %v", err)
+	}
+
+	for _, c := range []struct {
+		name string
+		tee  []byte
+	}{{
+		name: "different format",
+		tee:  write16PCM(t, FileFormat{Format: PCM, BitDepth: 16, Channels: 1, SampleRate: 48000}, samples),
+	}, {
+		name: "fewer samples",
+		tee:  write16PCM(t, ff, [][]int16{samples[0][:4]}),
+	}, {
+		name: "not a wav file",
+		tee:  bytes.Repeat([]byte{0xAB}, 100),
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			w, err := NewWriter(&testio.DiscardSeeker{}, ff, WithReadback(bytes.NewReader(c.tee)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := w.Write16PCM(samples); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err == nil {
+				t.Error("Close with a mismatched readback: expected error")
+			}
+		})
+	}
+}
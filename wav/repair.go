@@ -0,0 +1,50 @@
+package wav
+
+import (
+	"io"
+)
+
+// Repair rewrites the wav file in src to dst, making the size of the data chunk
+// match the audio that is actually there. Files from recordings that crashed
+// often declare more audio than they contain, which makes Samples too big and
+// reads fail when they hit the end of the file. Any partial frame at the end is
+// dropped. Other chunks are copied as with CopyMetadata.
+func Repair(dst io.WriteSeeker, src io.ReadSeeker) error {
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	r, err := NewReader(src)
+	if err != nil {
+		return err
+	}
+	// NewReader stops at the start of the audio, so the rest of src
+	// is all that's left for the data chunk.
+	start, err := src.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	end, err := src.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if _, err := src.Seek(start, io.SeekStart); err != nil {
+		return err
+	}
+	n := end - start
+	if r.dataBytes >= 0 {
+		n = min(n, r.dataBytes)
+	}
+	n -= n % int64(r.fmt.blockAlign)
+
+	w, err := r.EquivalentWriter(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.CopyN(w, r, n); err != nil {
+		return err
+	}
+	if err := CopyMetadata(w, r); err != nil {
+		return err
+	}
+	return w.Close()
+}
@@ -0,0 +1,73 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRepair(t *testing.T) {
+	const n = 500
+	samples := makeSlices[int16](2, n)
+	for i := range n {
+		samples[0][i] = int16(i * 13)
+		samples[1][i] = int16(-i * 7)
+	}
+	raw := write16PCM(t, FileFormat{
+		Format:     PCM,
+		BitDepth:   16,
+		Channels:   2,
+		SampleRate: 44100,
+	}, samples)
+	// Pretend the recording crashed part way through a frame, after
+	// promising a lot more audio.
+	raw = append(raw, 1, 2, 3)
+	i := bytes.Index(raw, []byte("data"))
+	if i < 0 {
+		t.Fatal("no data chunk in written file")
+	}
+	binary.LittleEndian.PutUint32(raw[i+4:], 1<<20)
+
+	r, err := NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := r.Samples(); got != 1<<18 {
+		t.Fatalf("broken file: Samples() = %d, want %d", got, 1<<18)
+	}
+
+	path := filepath.Join(t.TempDir(), "repaired.wav")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Repair(f, bytes.NewReader(raw)); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	repaired, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err = NewReader(bytes.NewReader(repaired))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := r.Samples(); got != n {
+		t.Errorf("repaired file: Samples() = %d, want %d", got, n)
+	}
+	got, err := ReadFull16PCM(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff(got, samples); d != "" {
+		t.Errorf("repaired samples mismatch (-got, +want):\n%v", d)
+	}
+}
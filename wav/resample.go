@@ -0,0 +1,107 @@
+package wav
+
+import (
+	"fmt"
+	"math"
+)
+
+// ResampleOption configures how Resample works.
+type ResampleOption func(*resampleOptions)
+
+type resampleOptions struct {
+	// taps is the length of the windowed-sinc kernel, or 0 for linear
+	// interpolation.
+	taps int
+}
+
+// WithSincResampling makes Resample use a windowed-sinc filter with the given
+// number of taps instead of linear interpolation. This is much more faithful,
+// with a flat frequency response and very little aliasing, but each output
+// sample costs taps multiplications instead of two. Somewhere around 32 taps
+// is plenty for playback, more gives a sharper cutoff near the Nyquist
+// frequency. It panics if taps isn't positive.
+func WithSincResampling(taps int) ResampleOption {
+	if taps <= 0 {
+		panic(fmt.Sprintf("wav: sinc resampling with %d taps", taps))
+	}
+	return func(o *resampleOptions) {
+		o.taps = taps
+	}
+}
+
+// Resample converts samples from one sample rate to another, returning a new
+// buffer. By default it linearly interpolates between samples, which is fast
+// and fine for previews, but aliases badly. See WithSincResampling for better
+// quality. It panics if either sample rate isn't positive.
+func Resample(samples [][]float32, from, to int, opts ...ResampleOption) [][]float32 {
+	if from <= 0 || to <= 0 {
+		panic(fmt.Sprintf("wav: resampling from %dHz to %dHz", from, to))
+	}
+	var o resampleOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	out := make([][]float32, len(samples))
+	for c, in := range samples {
+		n := int(int64(len(in)) * int64(to) / int64(from))
+		ch := make([]float32, n)
+		for i := range ch {
+			// Where output sample i falls in the input.
+			t := float64(i) * float64(from) / float64(to)
+			if o.taps > 0 {
+				ch[i] = sincAt(in, t, o.taps, min(1, float64(to)/float64(from)))
+			} else {
+				ch[i] = linearAt(in, t)
+			}
+		}
+		out[c] = ch
+	}
+	return out
+}
+
+// linearAt interpolates between the two samples either side of position t.
+func linearAt(in []float32, t float64) float32 {
+	j := int(t)
+	if j+1 >= len(in) {
+		return in[len(in)-1]
+	}
+	frac := float32(t - float64(j))
+	return in[j]*(1-frac) + in[j+1]*frac
+}
+
+// sincAt evaluates the band-limited signal in at position t, using a
+// Blackman-windowed sinc kernel of the given number of taps. cutoff is the
+// fraction of the input's Nyquist frequency to keep, which needs to be below 1
+// when downsampling to avoid aliasing. Samples past either end are treated as
+// silence.
+func sincAt(in []float32, t float64, taps int, cutoff float64) float32 {
+	half := float64(taps) / 2
+	// The kernel is stretched when downsampling, so it covers the same
+	// number of zero crossings at the lower cutoff.
+	width := half / cutoff
+	var sum float64
+	for k := int(math.Floor(t - width + 1)); k <= int(t+width); k++ {
+		if k < 0 || k >= len(in) {
+			continue
+		}
+		x := t - float64(k)
+		if math.Abs(x) >= width {
+			continue
+		}
+		sum += float64(in[k]) * cutoff * sinc(cutoff*x) * blackman(x/width)
+	}
+	return float32(sum)
+}
+
+// sinc is the normalised sinc function, sin(πx)/πx.
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// blackman is a Blackman window, centred on 0 and reaching 0 at ±1.
+func blackman(x float64) float64 {
+	return 0.42 + 0.5*math.Cos(math.Pi*x) + 0.08*math.Cos(2*math.Pi*x)
+}
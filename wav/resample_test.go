@@ -0,0 +1,76 @@
+package wav
+
+import (
+	"math"
+	"testing"
+)
+
+// sine returns a single channel with n samples of a full scale sine wave at
+// freq Hz, sampled at rate Hz.
+func sine(freq float64, rate, n int) [][]float32 {
+	out := makeSlices[float32](1, n)
+	for i := range out[0] {
+		out[0][i] = float32(math.Sin(2 * math.Pi * freq * float64(i) / float64(rate)))
+	}
+	return out
+}
+
+// rmsError returns the RMS difference between got and want, ignoring edge
+// samples at each end where the filter runs off the end of the input.
+func rmsError(got, want []float32, edge int) float64 {
+	var sum float64
+	n := min(len(got), len(want)) - 2*edge
+	for i := edge; i < edge+n; i++ {
+		d := float64(got[i] - want[i])
+		sum += d * d
+	}
+	return math.Sqrt(sum / float64(n))
+}
+
+func TestResampleAccuracy(t *testing.T) {
+	const (
+		from = 44100
+		to   = 48000
+		freq = 5000
+		n    = 44100
+	)
+	in := sine(freq, from, n)
+	want := sine(freq, to, n*to/from)
+
+	linear := Resample(in, from, to)
+	sinc := Resample(in, from, to, WithSincResampling(32))
+	if got, want := len(sinc[0]), n*to/from; got != want {
+		t.Fatalf("Resample output has %d samples, want %d", got, want)
+	}
+	linearErr := rmsError(linear[0], want[0], 100)
+	sincErr := rmsError(sinc[0], want[0], 100)
+	t.Logf("RMS error: linear %v, sinc %v", linearErr, sincErr)
+	if sincErr > 1e-3 {
+		t.Errorf("sinc RMS error = %v, want < 1e-3", sincErr)
+	}
+	if sincErr >= linearErr/10 {
+		t.Errorf("sinc RMS error %v not much better than linear %v", sincErr, linearErr)
+	}
+}
+
+func TestResampleAliasing(t *testing.T) {
+	// A tone above the Nyquist frequency of the output should be filtered
+	// out, rather than folding back down to 6kHz.
+	const (
+		from = 48000
+		to   = 16000
+		n    = 48000
+	)
+	in := sine(10000, from, n)
+	silence := makeSlices[float32](1, n*to/from)
+
+	linearRMS := rmsError(Resample(in, from, to)[0], silence[0], 100)
+	sincRMS := rmsError(Resample(in, from, to, WithSincResampling(64))[0], silence[0], 100)
+	t.Logf("aliased RMS: linear %v, sinc %v", linearRMS, sincRMS)
+	if sincRMS > 0.01 {
+		t.Errorf("sinc aliased RMS = %v, want < 0.01", sincRMS)
+	}
+	if linearRMS < 0.1 {
+		t.Errorf("linear aliased RMS = %v, expected it to alias", linearRMS)
+	}
+}
@@ -0,0 +1,106 @@
+package wav
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/pfcm/audiofile/riff"
+)
+
+// ReaderOption configures optional behaviour of a Reader.
+type ReaderOption func(*readerOptions)
+
+type readerOptions struct {
+	skipCorrupt bool
+}
+
+// WithSkipCorruptChunks makes NewReader skip chunks before the audio that are
+// obviously corrupt, such as ones that claim to be bigger than the rest of the
+// file, instead of giving up. It looks for the next thing that could be a chunk
+// header and carries on from there, and reports what it skipped in Warnings.
+// This is meant for salvaging damaged files. It only works if the io.Reader
+// passed to NewReader is also an io.Seeker, otherwise it has no effect.
+func WithSkipCorruptChunks() ReaderOption {
+	return func(o *readerOptions) {
+		o.skipCorrupt = true
+	}
+}
+
+// resyncer finds chunk boundaries again after a corrupt chunk.
+type resyncer struct {
+	rr *riff.Reader
+	rs io.ReadSeeker
+	// end is the size of the whole file.
+	end int64
+	// start is the position of the header of the most recently checked
+	// chunk.
+	start int64
+}
+
+// newResyncer returns a resyncer for the file being read by rr, or nil if r
+// can't seek.
+func newResyncer(r io.Reader, rr *riff.Reader) (*resyncer, error) {
+	rs, ok := r.(io.ReadSeeker)
+	if !ok {
+		return nil, nil
+	}
+	pos, err := rs.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	end, err := rs.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := rs.Seek(pos, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return &resyncer{rr: rr, rs: rs, end: end}, nil
+}
+
+// fits reports whether c, which must have just been read, fits in the file.
+func (rs *resyncer) fits(c *riff.Chunk) (bool, error) {
+	pos, err := rs.rs.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return false, err
+	}
+	rs.start = pos - 8
+	return pos+int64(c.Size) <= rs.end, nil
+}
+
+// resync moves the riff.Reader to the next plausible chunk header after the
+// start of the most recently checked chunk, or to the end of the file if there
+// isn't one.
+func (rs *resyncer) resync() error {
+	buf := make([]byte, 4096)
+	for off := rs.start + 1; off+8 <= rs.end; {
+		if _, err := rs.rs.Seek(off, io.SeekStart); err != nil {
+			return err
+		}
+		n, err := io.ReadFull(rs.rs, buf)
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		for i := 0; i+8 <= n; i++ {
+			if rs.plausible(off+int64(i), buf[i:i+8]) {
+				return rs.rr.SeekChunk(off + int64(i))
+			}
+		}
+		// Go back a little so headers that straddle the end of the
+		// buffer are found.
+		off += int64(max(n-7, 1))
+	}
+	return rs.rr.SeekChunk(rs.end)
+}
+
+// plausible reports whether hdr, found at offset, looks like a chunk header: a
+// printable ASCII identifier and a size that fits in the file.
+func (rs *resyncer) plausible(offset int64, hdr []byte) bool {
+	for _, b := range hdr[:4] {
+		if b < ' ' || b > '~' {
+			return false
+		}
+	}
+	size := binary.LittleEndian.Uint32(hdr[4:])
+	return offset+8+int64(size) <= rs.end
+}
@@ -0,0 +1,51 @@
+package wav
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSkipCorruptChunks(t *testing.T) {
+	good := writeRIFF(t, "WAVE",
+		rawChunk("fmt ", pcm16Fmt()),
+		rawChunk("afsp", []byte("AFsp"+"program: CopyAudio\x00\x00")),
+		rawChunk("data", cat(uint16le(1), uint16le(2), uint16le(3))),
+	)
+	// A chunk claiming to be far bigger than the file, followed by some
+	// garbage, between the fmt and afsp chunks.
+	garbage := cat([]byte("zzzz"), uint32le(0x7ffffff0), bytes.Repeat([]byte{0xff}, 11))
+	i := bytes.Index(good, []byte("afsp"))
+	raw := slices.Concat(good[:i], garbage, good[i:])
+
+	if _, err := NewReader(bytes.NewReader(raw)); err == nil {
+		t.Error("NewReader on a corrupt file without WithSkipCorruptChunks: expected error")
+	}
+	if _, err := NewReader(bytes.NewBuffer(raw), WithSkipCorruptChunks()); err == nil {
+		t.Error("NewReader on a corrupt file that can't seek: expected error")
+	}
+
+	r, err := NewReader(bytes.NewReader(raw), WithSkipCorruptChunks())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Warnings()) != 1 {
+		t.Errorf("Warnings() = %q, want one warning about the corrupt chunk", r.Warnings())
+	}
+	info, err := r.AFspInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff(info, []string{"program: CopyAudio"}); d != "" {
+		t.Errorf("AFspInfo() mismatch (-got, +want):\n%v", d)
+	}
+	got, err := ReadFull16PCM(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff(got, [][]int16{{1, 2, 3}}); d != "" {
+		t.Errorf("ReadFull16PCM() mismatch (-got, +want):\n%v", d)
+	}
+}
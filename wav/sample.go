@@ -3,6 +3,7 @@ package wav
 import (
 	"encoding/binary"
 	"fmt"
+	"io"
 	"iter"
 	"math"
 	"slices"
@@ -21,27 +22,47 @@ func from8PCMToFloat64(b byte) float64 {
 	return float64(b)*div - 1
 }
 
-const maxInt16 = int16(1<<15 - 1)
+const (
+	maxInt16 = int16(1<<15 - 1)
+	maxInt24 = int32(1<<23 - 1)
+)
 
 func from16PCMTo8PCM(i int16) byte       { return byte((i >> 8) + 128) }
 func from16PCMTo24PCM(i int16) int32     { return int32(i) << 8 }
 func from16PCMToFloat32(i int16) float32 { return float32(i) / float32(maxInt16) }
-func from16PCMToFloat64(i int16) float64 { panic("not implemented") }
+func from16PCMToFloat64(i int16) float64 { return float64(i) / float64(maxInt16) }
 
 func from24PCMTo8PCM(i int32) byte       { return byte((i >> 16) + 128) }
 func from24PCMTo16PCM(i int32) int16     { return int16(i >> 8) }
-func from24PCMToFloat32(i int32) float32 { panic("not implemented") }
-func from24PCMToFloat64(i int32) float64 { panic("not implemented") }
+func from24PCMToFloat32(i int32) float32 { return float32(i) / float32(maxInt24) }
+func from24PCMToFloat64(i int32) float64 { return float64(i) / float64(maxInt24) }
+
+// The float to PCM conversions round to the nearest value, rather than
+// truncating towards zero, which would bias the signal.
+
+func fromFloat32To8PCM(f float32) byte   { return fromFloat64To8PCM(float64(f)) }
+func fromFloat32To16PCM(f float32) int16 { return fromFloat64To16PCM(float64(f)) }
+func fromFloat32To24PCM(f float32) int32 { return fromFloat64To24PCM(float64(f)) }
 
-func fromFloat32To8PCM(f float32) byte       { return byte((f + 1) * 128) }
-func fromFloat32To16PCM(f float32) int16     { return int16(f * float32(maxInt16)) }
-func fromFloat32To24PCM(f float32) int32     { panic("not implemented") }
 func fromFloat32ToFloat64(f float32) float64 { return float64(f) }
 
-func fromFloat64To8PCM(f float64) byte     { return byte((f + 1) * 128) }
-func fromFloat64To16PCM(f float64) int16   { panic("not implemented") }
-func fromFloat64To24PCM(f float64) int32   { panic("not implemented") }
-func fromFloat64To32PCM(f float64) float32 { return float32(f) }
+func fromFloat64To8PCM(f float64) byte {
+	return byte(min(math.Round((clamp(f, -1, 1)+1)*128), 255))
+}
+
+func fromFloat64To16PCM(f float64) int16 {
+	return int16(math.Round(clamp(f, -1, 1) * float64(maxInt16)))
+}
+
+func fromFloat64To24PCM(f float64) int32 {
+	return int32(math.Round(clamp(f, -1, 1) * float64(maxInt24)))
+}
+
+func fromFloat64ToFloat32(f float64) float32 { return float32(f) }
+
+// clamp limits f to [lo, hi], so that out of range samples clip instead of
+// wrapping around when converted to an integer.
+func clamp(f, lo, hi float64) float64 { return max(lo, min(f, hi)) }
 
 func as8PCM(b []byte) iter.Seq[byte] { return slices.Values(b) }
 
@@ -68,7 +89,9 @@ func as24PCM(b []byte) (iter.Seq[int32], error) {
 		for len(b) > 0 {
 			// Little endian, hopefully.
 			lo, mid, hi := int32(b[0]), int32(b[1]), int32(b[2])
-			i := lo & (mid << 8) & (hi << 16)
+			i := lo | mid<<8 | hi<<16
+			// Shift up and back down to extend the sign bit.
+			i = i << 8 >> 8
 			if !yield(i) {
 				return
 			}
@@ -93,7 +116,7 @@ func asFloat32(b []byte) (iter.Seq[float32], error) {
 }
 
 func asFloat64(b []byte) (iter.Seq[float64], error) {
-	if err := checkSize(4, b); err != nil {
+	if err := checkSize(8, b); err != nil {
 		return nil, err
 	}
 	return func(yield func(float64) bool) {
@@ -132,3 +155,83 @@ func checkSize(bytesPerSample int, b []byte) error {
 	}
 	return nil
 }
+
+// decodeAll returns an iterator over the samples in b, decoded with next.
+func decodeAll[T any](b []byte, next func([]byte) (T, []byte)) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for len(b) > 0 {
+			var t T
+			t, b = next(b)
+			if !yield(t) {
+				return
+			}
+		}
+	}
+}
+
+// Frames16 returns an iterator that reads the audio from r bufSize frames at a
+// time, converted to 16 bit PCM, so large files can be processed without
+// reading them all at once. Each block has a slice per channel, and is only
+// valid until the next iteration. The last block may be shorter than bufSize.
+// If there is an error it is yielded with a nil block and the iteration stops.
+// Like the Read methods, if the file is cut off part way through a frame, the
+// whole frames before it are yielded and then io.ErrUnexpectedEOF.
+func Frames16(r *Reader, bufSize int) iter.Seq2[[][]int16, error] {
+	return func(yield func([][]int16, error) bool) {
+		next, err := r.int16Decoder()
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		frames(r, bufSize, next, yield)
+	}
+}
+
+// frames reads blocks of bufSize frames from r, decodes them with next and
+// passes them to yield until the audio runs out or yield returns false.
+func frames[T any](r *Reader, bufSize int, next func([]byte) (T, []byte), yield func([][]T, error) bool) {
+	if bufSize < 1 {
+		yield(nil, fmt.Errorf("buffer size %d, must be at least 1", bufSize))
+		return
+	}
+	var (
+		channels   = r.Channels()
+		blockAlign = int(r.fmt.blockAlign)
+		block      = makeSlices[T](channels, bufSize)
+		out        = make([][]T, channels)
+	)
+	for {
+		raw, err := r.readN(bufSize * blockAlign)
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		// A partial frame means the file was cut off, which is
+		// reported after the whole frames before it.
+		partial := len(raw)%blockAlign != 0
+		raw = raw[:len(raw)-len(raw)%blockAlign]
+		n := 0
+		for frame := range deinterleave(channels, decodeAll(raw, next)) {
+			for c, s := range frame {
+				block[c][n] = s
+			}
+			n++
+		}
+		for c := range block {
+			out[c] = block[c][:n]
+		}
+		if n > 0 && !yield(out, nil) {
+			return
+		}
+		if partial {
+			yield(nil, io.ErrUnexpectedEOF)
+			return
+		}
+		if n == 0 {
+			return
+		}
+	}
+}
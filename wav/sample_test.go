@@ -1,6 +1,8 @@
 package wav
 
 import (
+	"bytes"
+	"io"
 	"iter"
 	"slices"
 	"strconv"
@@ -19,17 +21,36 @@ func TestRoundTrip(t *testing.T) {
 			}
 		}
 	}
-	sixteenBitValues := func() iter.Seq[int16] {
-		return func(yield func(int16) bool) {
-			// Seems surprising to test every value, but it's fast
-			// enough for now.
-			for i := -32768; i <= 32767; i += 1 {
-				if !yield(int16(i)) {
-					return
+	// The most negative integers are just below -1 as floats, so they
+	// come back from a float as the next value up and are left out of the
+	// float round trips.
+	sixteenBitValuesFrom := func(lo int) func() iter.Seq[int16] {
+		return func() iter.Seq[int16] {
+			return func(yield func(int16) bool) {
+				// Seems surprising to test every value, but it's
+				// fast enough for now.
+				for i := lo; i <= 32767; i += 1 {
+					if !yield(int16(i)) {
+						return
+					}
 				}
 			}
 		}
 	}
+	twentyFourBitValuesFrom := func(lo int32) func() iter.Seq[int32] {
+		return func() iter.Seq[int32] {
+			return func(yield func(int32) bool) {
+				for i := lo; i < 1<<23; i++ {
+					if !yield(i) {
+						return
+					}
+				}
+			}
+		}
+	}
+	sixteenBitValues := sixteenBitValuesFrom(-1 << 15)
+	symmetricSixteenBitValues := sixteenBitValuesFrom(-(1<<15 - 1))
+	symmetricTwentyFourBitValues := twentyFourBitValuesFrom(-(1<<23 - 1))
 	// First all the round trips that don't involve any loss of precision.
 	for _, c := range []struct {
 		name string
@@ -51,7 +72,16 @@ func TestRoundTrip(t *testing.T) {
 		test: mkRoundTripTest(from16PCMTo24PCM, from24PCMTo16PCM, sixteenBitValues),
 	}, {
 		name: "16PCM/Float32",
-		test: mkRoundTripTest(from16PCMToFloat32, fromFloat32To16PCM, sixteenBitValues),
+		test: mkRoundTripTest(from16PCMToFloat32, fromFloat32To16PCM, symmetricSixteenBitValues),
+	}, {
+		name: "16PCM/Float64",
+		test: mkRoundTripTest(from16PCMToFloat64, fromFloat64To16PCM, symmetricSixteenBitValues),
+	}, {
+		name: "24PCM/Float32",
+		test: mkRoundTripTest(from24PCMToFloat32, fromFloat32To24PCM, symmetricTwentyFourBitValues),
+	}, {
+		name: "24PCM/Float64",
+		test: mkRoundTripTest(from24PCMToFloat64, fromFloat64To24PCM, symmetricTwentyFourBitValues),
 	}} {
 		t.Run(c.name, c.test)
 	}
@@ -69,6 +99,62 @@ func mkRoundTripTest[A comparable, B any](aToB func(A) B, bToA func(B) A, values
 	}
 }
 
+func TestFloatToPCMClips(t *testing.T) {
+	for _, c := range []struct {
+		name      string
+		got, want int32
+	}{
+		{"Float32/24PCM high", fromFloat32To24PCM(1.5), 1<<23 - 1},
+		{"Float32/24PCM low", fromFloat32To24PCM(-1.5), -(1<<23 - 1)},
+		{"Float64/24PCM high", fromFloat64To24PCM(2), 1<<23 - 1},
+		{"Float64/24PCM low", fromFloat64To24PCM(-2), -(1<<23 - 1)},
+		{"Float64/16PCM high", int32(fromFloat64To16PCM(1.01)), 1<<15 - 1},
+		{"Float64/16PCM low", int32(fromFloat64To16PCM(-1.01)), -(1<<15 - 1)},
+		{"Float32/16PCM high", int32(fromFloat32To16PCM(1.5)), 1<<15 - 1},
+		{"Float32/16PCM low", int32(fromFloat32To16PCM(-1.5)), -(1<<15 - 1)},
+		{"Float32/8PCM high", int32(fromFloat32To8PCM(1.5)), 255},
+		{"Float32/8PCM low", int32(fromFloat32To8PCM(-1.5)), 0},
+		{"Float64/8PCM high", int32(fromFloat64To8PCM(1.5)), 255},
+		{"Float64/8PCM low", int32(fromFloat64To8PCM(-1.5)), 0},
+		{"Float64/8PCM very high", int32(fromFloat64To8PCM(100)), 255},
+		{"Float64/8PCM very low", int32(fromFloat64To8PCM(-100)), 0},
+	} {
+		if c.got != c.want {
+			t.Errorf("%s: got %d, want %d", c.name, c.got, c.want)
+		}
+	}
+}
+
+func TestFloatToPCMRounds(t *testing.T) {
+	for _, c := range []struct {
+		name      string
+		got, want int32
+	}{
+		{"Float32/16PCM 1", int32(fromFloat32To16PCM(1)), 1<<15 - 1},
+		{"Float32/16PCM -1", int32(fromFloat32To16PCM(-1)), -(1<<15 - 1)},
+		{"Float32/16PCM 0.99997", int32(fromFloat32To16PCM(0.99997)), 1<<15 - 2},
+		{"Float32/16PCM -0.99997", int32(fromFloat32To16PCM(-0.99997)), -(1<<15 - 2)},
+		{"Float32/16PCM up", int32(fromFloat32To16PCM(2.6 / 32767)), 3},
+		{"Float32/16PCM down", int32(fromFloat32To16PCM(-2.6 / 32767)), -3},
+		{"Float64/16PCM up", int32(fromFloat64To16PCM(2.6 / 32767)), 3},
+		{"Float64/16PCM down", int32(fromFloat64To16PCM(-2.6 / 32767)), -3},
+		{"Float32/24PCM 1", fromFloat32To24PCM(1), 1<<23 - 1},
+		{"Float64/24PCM up", fromFloat64To24PCM(2.6 / (1<<23 - 1)), 3},
+		{"Float64/24PCM down", fromFloat64To24PCM(-2.6 / (1<<23 - 1)), -3},
+		{"Float32/8PCM 1", int32(fromFloat32To8PCM(1)), 255},
+		{"Float32/8PCM -1", int32(fromFloat32To8PCM(-1)), 0},
+		{"Float32/8PCM 0.99", int32(fromFloat32To8PCM(0.99)), 255},
+		{"Float64/8PCM up", int32(fromFloat64To8PCM(0.6 / 128)), 129},
+		{"Float64/8PCM down", int32(fromFloat64To8PCM(-0.6 / 128)), 127},
+		{"Float32/16PCM lowest", int32(fromFloat32To16PCM(from16PCMToFloat32(-1 << 15))), -(1<<15 - 1)},
+		{"Float64/24PCM lowest", fromFloat64To24PCM(from24PCMToFloat64(-1 << 23)), -(1<<23 - 1)},
+	} {
+		if c.got != c.want {
+			t.Errorf("%s: got %d, want %d", c.name, c.got, c.want)
+		}
+	}
+}
+
 func TestDeinterleave(t *testing.T) {
 	const num = 10
 	in := make([]int, num)
@@ -105,3 +191,137 @@ func TestDeinterleave(t *testing.T) {
 		})
 	}
 }
+
+func TestAs24PCM(t *testing.T) {
+	for _, c := range []struct {
+		in   []byte
+		want int32
+	}{
+		{in: []byte{0x00, 0x00, 0x00}, want: 0},
+		{in: []byte{0x01, 0x00, 0x00}, want: 1},
+		{in: []byte{0x56, 0x34, 0x12}, want: 0x123456},
+		{in: []byte{0xff, 0xff, 0x7f}, want: 0x7fffff},
+		{in: []byte{0x00, 0x00, 0x80}, want: -0x800000},
+		{in: []byte{0xff, 0xff, 0xff}, want: -1},
+		{in: []byte{0x00, 0x01, 0xff}, want: -0xff00},
+	} {
+		it, err := as24PCM(c.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := slices.Collect(it)
+		if d := cmp.Diff(got, []int32{c.want}); d != "" {
+			t.Errorf("as24PCM(% x): mismatch (-got, +want):\n%v", c.in, d)
+		}
+	}
+}
+
+func TestAsFloat64BadLength(t *testing.T) {
+	// A whole number of 32 bit floats, but not 64 bit ones.
+	if _, err := asFloat64(make([]byte, 12)); err == nil {
+		t.Error("asFloat64(12 bytes): expected error")
+	}
+}
+
+func TestFrames16(t *testing.T) {
+	const n = 1000
+	samples := makeSlices[int16](2, n)
+	for i := range n {
+		samples[0][i] = int16(i)
+		samples[1][i] = int16(-i)
+	}
+	raw := write16PCM(t, FileFormat{
+		Format:     PCM,
+		BitDepth:   16,
+		Channels:   2,
+		SampleRate: 44100,
+	}, samples)
+	r, err := NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make([][]int16, 2)
+	var sizes []int
+	for block, err := range Frames16(r, 300) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		sizes = append(sizes, len(block[0]))
+		for c := range block {
+			got[c] = append(got[c], block[c]...)
+		}
+	}
+	if d := cmp.Diff(sizes, []int{300, 300, 300, 100}); d != "" {
+		t.Errorf("block sizes mismatch (-got, +want):\n%v", d)
+	}
+	if d := cmp.Diff(got, samples); d != "" {
+		t.Errorf("Frames16 mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestFrames16Truncated(t *testing.T) {
+	const n = 10
+	samples := makeSlices[int16](2, n)
+	for i := range n {
+		samples[0][i] = int16(i)
+		samples[1][i] = int16(-i)
+	}
+	raw := write16PCM(t, FileFormat{
+		Format:     PCM,
+		BitDepth:   16,
+		Channels:   2,
+		SampleRate: 44100,
+	}, samples)
+	// Cut the file off one byte into the eighth frame.
+	const frames = 7
+	start := bytes.Index(raw, []byte("data")) + 8
+	r, err := NewReader(bytes.NewReader(raw[:start+frames*4+1]))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make([][]int16, 2)
+	var lastErr error
+	for block, err := range Frames16(r, 4) {
+		if err != nil {
+			lastErr = err
+			break
+		}
+		for c := range block {
+			got[c] = append(got[c], block[c]...)
+		}
+	}
+	if lastErr != io.ErrUnexpectedEOF {
+		t.Errorf("Frames16 on a truncated file: got error %v, want %v", lastErr, io.ErrUnexpectedEOF)
+	}
+	want := [][]int16{samples[0][:frames], samples[1][:frames]}
+	if d := cmp.Diff(got, want); d != "" {
+		t.Errorf("Frames16 mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestFrames16BadBufSize(t *testing.T) {
+	raw := write16PCM(t, FileFormat{
+		Format:     PCM,
+		BitDepth:   16,
+		Channels:   1,
+		SampleRate: 44100,
+	}, [][]int16{{1, 2, 3}})
+	for _, bufSize := range []int{0, -1} {
+		r, err := NewReader(bytes.NewReader(raw))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var errs int
+		for block, err := range Frames16(r, bufSize) {
+			if err == nil {
+				t.Fatalf("Frames16 with buffer size %d yielded a block of %d frames, want an error", bufSize, len(block[0]))
+			}
+			errs++
+		}
+		if errs != 1 {
+			t.Errorf("Frames16 with buffer size %d yielded %d errors, want 1", bufSize, errs)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package wav
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// SamplerInfo holds the contents of a smpl chunk, which describes how a sampler
+// should play the audio.
+type SamplerInfo struct {
+	// Manufacturer and Product identify the sampler the chunk is for, or
+	// are 0 if it isn't for any particular one.
+	Manufacturer, Product uint32
+	// SamplePeriod is the length of a sample in nanoseconds.
+	SamplePeriod uint32
+	// UnityNote is the MIDI note that plays the audio at its original
+	// pitch, 60 being middle C.
+	UnityNote uint32
+	// PitchFraction tunes UnityNote upwards by a fraction of a semitone,
+	// scaled so that 0x80000000 is half a semitone.
+	PitchFraction uint32
+	// SMPTEFormat is the frame rate of SMPTEOffset (24, 25, 29 or 30), or
+	// 0 if there is no offset. SMPTEOffset is the time the audio should
+	// start, packed as hours, minutes, seconds and frames.
+	SMPTEFormat, SMPTEOffset uint32
+	// Loops are the sample loops, if there are any.
+	Loops []Loop
+}
+
+// Loop is a sample loop from a smpl chunk, as used by samplers.
+type Loop struct {
+	// ID identifies the loop, and may match a cue point.
+	ID uint32
+	// Type is 0 to loop forwards, 1 to alternate forwards and backwards
+	// and 2 to loop backwards. Other values are manufacturer specific.
+	Type uint32
+	// Start and End are the positions of the first and last samples of the
+	// loop, in sample frames from the start of the audio.
+	Start, End uint32
+	// Fraction fine tunes the loop points, as a fraction of a sample
+	// scaled so that 0x80000000 is half a sample.
+	Fraction uint32
+	// PlayCount is the number of times to play the loop, 0 means forever.
+	PlayCount uint32
+}
+
+// smplFixedSize is the size of the fields before the loops: manufacturer,
+// product, sample period, MIDI unity note and pitch fraction, SMPTE format and
+// offset, the number of loops and the size of the sampler data.
+const smplFixedSize = 9 * 4
+
+// smplLoopSize is the size of each loop in a smpl chunk.
+const smplLoopSize = 6 * 4
+
+// Loops returns the sample loops from the file's smpl chunk, or nil if it
+// doesn't have one. A smpl chunk without any loops gives an empty slice. The
+// chunks after the audio data are searched too, as with SamplerInfo.
+func (r *Reader) Loops() ([]Loop, error) {
+	info, err := r.SamplerInfo()
+	if info == nil || err != nil {
+		return nil, err
+	}
+	return info.Loops, nil
+}
+
+// SamplerInfo returns the contents of the file's smpl chunk, including its
+// loops, or nil if it doesn't have one. The chunks after the audio data are
+// searched too, as with AllMetadata.
+func (r *Reader) SamplerInfo() (*SamplerInfo, error) {
+	all, err := r.allMetadata()
+	if err != nil {
+		return nil, err
+	}
+	for _, mc := range all {
+		if mc.id == "smpl" {
+			return parseSmpl(mc.data)
+		}
+	}
+	return nil, nil
+}
+
+func parseSmpl(raw []byte) (*SamplerInfo, error) {
+	if len(raw) < smplFixedSize {
+		return nil, fmt.Errorf("smpl chunk too short: %d bytes, need at least %d", len(raw), smplFixedSize)
+	}
+	get32 := func() uint32 {
+		x := binary.LittleEndian.Uint32(raw)
+		raw = raw[4:]
+		return x
+	}
+	info := &SamplerInfo{
+		Manufacturer:  get32(),
+		Product:       get32(),
+		SamplePeriod:  get32(),
+		UnityNote:     get32(),
+		PitchFraction: get32(),
+		SMPTEFormat:   get32(),
+		SMPTEOffset:   get32(),
+	}
+	n := get32()
+	// The size of the sampler specific data at the end, which isn't
+	// needed.
+	get32()
+	if uint64(n)*smplLoopSize > uint64(len(raw)) {
+		return nil, fmt.Errorf("smpl chunk has %d loops but only %d bytes for them", n, len(raw))
+	}
+	info.Loops = make([]Loop, n)
+	for i := range info.Loops {
+		info.Loops[i] = Loop{
+			ID:        get32(),
+			Type:      get32(),
+			Start:     get32(),
+			End:       get32(),
+			Fraction:  get32(),
+			PlayCount: get32(),
+		}
+	}
+	return info, nil
+}
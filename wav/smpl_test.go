@@ -0,0 +1,156 @@
+package wav
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pfcm/audiofile/riff"
+)
+
+// smplChunk builds a smpl chunk holding the given loops.
+func smplChunk(loops ...Loop) *riff.Chunk {
+	data := cat(
+		uint32le(0),     // manufacturer
+		uint32le(0),     // product
+		uint32le(22675), // sample period, in nanoseconds
+		uint32le(60),    // MIDI unity note
+		uint32le(1<<31), // MIDI pitch fraction
+		uint32le(0),     // SMPTE format
+		uint32le(0),     // SMPTE offset
+		uint32le(uint32(len(loops))),
+		uint32le(0), // sampler data
+	)
+	for _, l := range loops {
+		data = cat(data,
+			uint32le(l.ID),
+			uint32le(l.Type),
+			uint32le(l.Start),
+			uint32le(l.End),
+			uint32le(l.Fraction),
+			uint32le(l.PlayCount),
+		)
+	}
+	return rawChunk("smpl", data)
+}
+
+func TestLoops(t *testing.T) {
+	two := []Loop{
+		{ID: 1, Type: 0, Start: 100, End: 2000},
+		{ID: 2, Type: 1, Start: 2500, End: 4000, Fraction: 1 << 31, PlayCount: 3},
+	}
+	full, err := io.ReadAll(smplChunk(two...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		name    string
+		chunks  []*riff.Chunk
+		want    []Loop
+		wantErr bool
+	}{{
+		name: "two loops",
+		chunks: []*riff.Chunk{
+			rawChunk("fmt ", pcm16Fmt()),
+			smplChunk(two...),
+			rawChunk("data", []byte{0, 0}),
+		},
+		want: two,
+	}, {
+		name: "after the data",
+		chunks: []*riff.Chunk{
+			rawChunk("fmt ", pcm16Fmt()),
+			rawChunk("data", []byte{0, 0}),
+			smplChunk(two...),
+		},
+		want: two,
+	}, {
+		name: "no loops",
+		chunks: []*riff.Chunk{
+			rawChunk("fmt ", pcm16Fmt()),
+			smplChunk(),
+			rawChunk("data", []byte{0, 0}),
+		},
+		want: []Loop{},
+	}, {
+		name: "no smpl chunk",
+		chunks: []*riff.Chunk{
+			rawChunk("fmt ", pcm16Fmt()),
+			rawChunk("data", []byte{0, 0}),
+		},
+		want: nil,
+	}, {
+		name: "too short",
+		chunks: []*riff.Chunk{
+			rawChunk("fmt ", pcm16Fmt()),
+			rawChunk("smpl", make([]byte, 20)),
+			rawChunk("data", []byte{0, 0}),
+		},
+		wantErr: true,
+	}, {
+		name: "missing loop",
+		chunks: []*riff.Chunk{
+			rawChunk("fmt ", pcm16Fmt()),
+			rawChunk("smpl", full[:len(full)-smplLoopSize]),
+			rawChunk("data", []byte{0, 0}),
+		},
+		wantErr: true,
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			r, err := NewReader(bytes.NewReader(writeRIFF(t, "WAVE", c.chunks...)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := r.Loops()
+			if err != nil {
+				if !c.wantErr {
+					t.Fatal(err)
+				}
+				return
+			}
+			if c.wantErr {
+				t.Fatalf("Loops() = %+v, expected error", got)
+			}
+			if d := cmp.Diff(got, c.want); d != "" {
+				t.Errorf("Loops() mismatch (-got, +want):\n%v", d)
+			}
+		})
+	}
+}
+
+func TestSamplerInfo(t *testing.T) {
+	loop := Loop{ID: 1, Start: 10, End: 20}
+	r, err := NewReader(bytes.NewReader(writeRIFF(t, "WAVE",
+		rawChunk("fmt ", pcm16Fmt()),
+		smplChunk(loop),
+		rawChunk("data", []byte{0, 0}),
+	)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := r.SamplerInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &SamplerInfo{
+		SamplePeriod:  22675,
+		UnityNote:     60,
+		PitchFraction: 1 << 31,
+		Loops:         []Loop{loop},
+	}
+	if d := cmp.Diff(got, want); d != "" {
+		t.Errorf("SamplerInfo() mismatch (-got, +want):\n%v", d)
+	}
+
+	r, err = NewReader(bytes.NewReader(writeRIFF(t, "WAVE",
+		rawChunk("fmt ", pcm16Fmt()),
+		rawChunk("data", []byte{0, 0}),
+	)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := r.SamplerInfo(); got != nil || err != nil {
+		t.Errorf("SamplerInfo() without a smpl chunk = %+v, %v, want nil, nil", got, err)
+	}
+}
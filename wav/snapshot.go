@@ -0,0 +1,49 @@
+package wav
+
+import (
+	"bytes"
+	"io"
+)
+
+// Snapshot holds all of the audio data from a Reader in memory, so that it can
+// be read many times, including concurrently.
+type Snapshot struct {
+	fmt      fmtChunk
+	data     []byte
+	metadata []metadataChunk
+	fact     int
+	hasFact  bool
+}
+
+// Snapshot reads the rest of the audio data from r and returns a Snapshot of
+// it. The data is not decoded, so it takes up the same amount of memory as it
+// did in the file. After calling Snapshot there is nothing left to read from r.
+func (r *Reader) Snapshot() (*Snapshot, error) {
+	data, err := io.ReadAll(r.data)
+	if err != nil {
+		return nil, err
+	}
+	return &Snapshot{
+		fmt:      r.fmt,
+		data:     data,
+		metadata: r.metadata,
+		fact:     r.fact,
+		hasFact:  r.hasFact,
+	}, nil
+}
+
+// Reader returns a new Reader that reads from the start of the snapshot. Each
+// Reader has its own position and buffers, so different goroutines can safely
+// use their own Readers from the same Snapshot at the same time.
+func (s *Snapshot) Reader() *Reader {
+	data := bytes.NewReader(s.data)
+	return &Reader{
+		fmt:       s.fmt,
+		data:      data,
+		seeker:    data,
+		dataBytes: int64(len(s.data)),
+		metadata:  s.metadata,
+		fact:      s.fact,
+		hasFact:   s.hasFact,
+	}
+}
@@ -0,0 +1,56 @@
+package wav
+
+import (
+	"bytes"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSnapshot(t *testing.T) {
+	raw, err := os.ReadFile("../testdata/kick.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := ReadFull32Float(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err = NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	snap, err := r.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const readers = 8
+	var (
+		wg   sync.WaitGroup
+		got  = make([][][]float32, readers)
+		errs = make([]error, readers)
+	)
+	for i := range readers {
+		wg.Go(func() {
+			got[i], errs[i] = ReadFull32Float(snap.Reader())
+		})
+	}
+	wg.Wait()
+	for i := range readers {
+		if errs[i] != nil {
+			t.Errorf("reader %d: %v", i, errs[i])
+			continue
+		}
+		if d := cmp.Diff(got[i], want); d != "" {
+			t.Errorf("reader %d: samples mismatch (-got, +want):\n%v", i, d)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package wav
+
+import "math/bits"
+
+// Speaker is a speaker position from the channel mask of an Extensible fmt
+// chunk. The values are the bits used in the mask.
+type Speaker uint32
+
+// The speaker positions, in the order channels are interleaved.
+const (
+	FrontLeft Speaker = 1 << iota
+	FrontRight
+	FrontCenter
+	LowFrequency
+	BackLeft
+	BackRight
+	FrontLeftOfCenter
+	FrontRightOfCenter
+	BackCenter
+	SideLeft
+	SideRight
+	TopCenter
+	TopFrontLeft
+	TopFrontCenter
+	TopFrontRight
+	TopBackLeft
+	TopBackCenter
+	TopBackRight
+)
+
+// ChannelMask returns the channel mask from an Extensible fmt chunk, which
+// says which speakers the channels are for. It is 0 if the file isn't
+// Extensible or doesn't say.
+func (r *Reader) ChannelMask() uint32 {
+	return r.fmt.channelMask
+}
+
+// SpeakerLayout returns the speaker for each channel, decoded from the channel
+// mask. Channels are interleaved in the order of the bits in the mask, so the
+// first channel is for the lowest bit that is set and so on. If there are more
+// channels than bits set in the mask, the extra channels aren't for any
+// particular speaker and the result is shorter than the number of channels. It
+// returns nil if there is no channel mask.
+func (r *Reader) SpeakerLayout() []Speaker {
+	mask := r.fmt.channelMask
+	if mask == 0 {
+		return nil
+	}
+	var layout []Speaker
+	for mask != 0 && len(layout) < r.Channels() {
+		bit := Speaker(1) << bits.TrailingZeros32(mask)
+		layout = append(layout, bit)
+		mask &^= uint32(bit)
+	}
+	return layout
+}
+
+// defaultChannelMask returns the usual channel mask for the number of channels,
+// or 0 if there isn't one.
+func defaultChannelMask(channels int) uint32 {
+	var layout Speaker
+	switch channels {
+	case 1:
+		layout = FrontCenter
+	case 2:
+		layout = FrontLeft | FrontRight
+	case 3:
+		layout = FrontLeft | FrontRight | FrontCenter
+	case 4:
+		layout = FrontLeft | FrontRight | BackLeft | BackRight
+	case 5:
+		layout = FrontLeft | FrontRight | FrontCenter | BackLeft | BackRight
+	case 6:
+		layout = FrontLeft | FrontRight | FrontCenter | LowFrequency | BackLeft | BackRight
+	case 7:
+		layout = FrontLeft | FrontRight | FrontCenter | LowFrequency | BackLeft | BackRight | BackCenter
+	case 8:
+		layout = FrontLeft | FrontRight | FrontCenter | LowFrequency | BackLeft | BackRight | SideLeft | SideRight
+	}
+	return uint32(layout)
+}
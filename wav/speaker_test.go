@@ -0,0 +1,66 @@
+package wav
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSpeakerLayout(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		channels uint16
+		mask     uint32
+		want     []Speaker
+	}{{
+		name:     "5.1",
+		channels: 6,
+		mask:     0x3F,
+		want:     []Speaker{FrontLeft, FrontRight, FrontCenter, LowFrequency, BackLeft, BackRight},
+	}, {
+		name:     "7.1 with side speakers",
+		channels: 8,
+		mask:     0x63F,
+		want:     []Speaker{FrontLeft, FrontRight, FrontCenter, LowFrequency, BackLeft, BackRight, SideLeft, SideRight},
+	}, {
+		name:     "more bits than channels",
+		channels: 2,
+		mask:     0x7,
+		want:     []Speaker{FrontLeft, FrontRight},
+	}, {
+		name:     "more channels than bits",
+		channels: 4,
+		mask:     0x4,
+		want:     []Speaker{FrontCenter},
+	}, {
+		name:     "no mask",
+		channels: 2,
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			fc := cat(
+				uint16le(uint16(Extensible)),
+				uint16le(c.channels),
+				uint32le(48000),
+				uint32le(48000*2*uint32(c.channels)),
+				uint16le(2*c.channels),
+				uint16le(16),
+				uint16le(22),
+				uint16le(16),
+				uint32le(c.mask),
+				mkSubformat(PCM),
+			)
+			raw := writeRIFF(t, "WAVE", rawChunk("fmt ", fc), rawChunk("data", nil))
+			r, err := NewReader(bytes.NewReader(raw))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := r.ChannelMask(); got != c.mask {
+				t.Errorf("ChannelMask() = %#x, want %#x", got, c.mask)
+			}
+			if d := cmp.Diff(r.SpeakerLayout(), c.want); d != "" {
+				t.Errorf("SpeakerLayout() mismatch (-got, +want):\n%v", d)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package wav
+
+import (
+	"errors"
+	"io"
+)
+
+// NewStreamWriter returns a Writer that writes to w, which doesn't have to be
+// able to seek, such as a pipe or a network connection. The sizes in the
+// headers aren't known until the end, so the whole file is held in memory and
+// only written to w when the Writer is closed. That means it needs as much
+// memory as the file is big, which rules it out for long recordings; NewWriter
+// should be used whenever there is something that can seek.
+func NewStreamWriter(w io.Writer, ff FileFormat, opts ...WriterOption) (*Writer, error) {
+	buf := &writeBuffer{}
+	wr, err := NewWriter(buf, ff, opts...)
+	if err != nil {
+		return nil, err
+	}
+	wr.stream = w
+	return wr, nil
+}
+
+// flushStream writes the whole file to the io.Writer passed to
+// NewStreamWriter, if there is one.
+func (w *Writer) flushStream() error {
+	if w.stream == nil {
+		return nil
+	}
+	_, err := w.stream.Write(w.ws.(*writeBuffer).buf)
+	return err
+}
+
+// writeBuffer is an io.WriteSeeker that keeps everything in memory.
+type writeBuffer struct {
+	buf []byte
+	pos int64
+}
+
+func (b *writeBuffer) Write(p []byte) (int, error) {
+	if end := b.pos + int64(len(p)); end > int64(len(b.buf)) {
+		b.buf = append(b.buf, make([]byte, end-int64(len(b.buf)))...)
+	}
+	n := copy(b.buf[b.pos:], p)
+	b.pos += int64(n)
+	return n, nil
+}
+
+func (b *writeBuffer) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += b.pos
+	case io.SeekEnd:
+		offset += int64(len(b.buf))
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+	b.pos = offset
+	return offset, nil
+}
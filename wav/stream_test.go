@@ -0,0 +1,46 @@
+package wav
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestStreamWriter(t *testing.T) {
+	// Float, so the fact chunk has to be filled in too.
+	ff := FileFormat{Format: IEEEFloat, BitDepth: 32, Channels: 2, SampleRate: 48000}
+	want := [][]float32{
+		{0, 0.5, -0.5, 0.25},
+		{1, -1, 0.125, 0},
+	}
+	var b bytes.Buffer
+	w, err := NewStreamWriter(&b, ff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write32Float(want); err != nil {
+		t.Fatal(err)
+	}
+	if b.Len() != 0 {
+		t.Errorf("%d bytes written before Close, want 0", b.Len())
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewReader(bytes.NewReader(b.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.hasFact || r.fact != len(want[0]) {
+		t.Errorf("fact chunk: present %v, count %d, want %d", r.hasFact, r.fact, len(want[0]))
+	}
+	got, err := ReadFull32Float(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff(got, want); d != "" {
+		t.Errorf("samples mismatch (-got, +want):\n%v", d)
+	}
+}
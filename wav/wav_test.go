@@ -3,12 +3,20 @@ package wav
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
+	"fmt"
 	"io"
+	"math"
 	"os"
 	"path/filepath"
+	"slices"
 	"testing"
+	"unsafe"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/pfcm/audiofile/internal/testio"
+	"github.com/pfcm/audiofile/riff"
 )
 
 func cat(bs ...[]byte) []byte {
@@ -66,6 +74,21 @@ func TestReadFmtChunk(t *testing.T) {
 			blockAlign:    2048,
 			bitsPerSample: 16,
 		},
+	}, {
+		name: "pcm with empty extension",
+		in:   cat(pcm16Fmt(), uint16le(0)),
+		out: &fmtChunk{
+			format:        PCM,
+			channels:      1,
+			sampleRate:    44100,
+			dataRate:      44100 * 2,
+			blockAlign:    2,
+			bitsPerSample: 16,
+		},
+	}, {
+		name: "pcm with bad extension size",
+		in:   cat(pcm16Fmt(), uint16le(22)),
+		out:  nil,
 	}, {
 		name: "normal mu-law",
 		in: cat(
@@ -141,6 +164,20 @@ func TestReadFmtChunk(t *testing.T) {
 			channelMask:        0,
 			subFormat:          PCM,
 		},
+	}, {
+		name: "too many valid bits",
+		in: cat(
+			uint16le(uint16(Extensible)),
+			uint16le(2),
+			uint32le(48000),
+			uint32le(48000*2*3),
+			uint16le(2*3),
+			uint16le(24),
+			uint16le(22),
+			uint16le(32),
+			uint32le(0),
+			mkSubformat(PCM),
+		),
 	}, {
 		name: "invalid subformat",
 		in: cat(
@@ -246,6 +283,33 @@ func TestRoundTrip16PCMTo16PCM(t *testing.T) {
 	diff(t, got, raw)
 }
 
+func TestWrite16PCMTo8PCM(t *testing.T) {
+	samples := [][]int16{{0, 0x7fff, -0x8000, 0x0100, 0x01ff, -0x0001, -0x0100}}
+	raw := write16PCM(t, FileFormat{
+		Format:     PCM,
+		BitDepth:   8,
+		Channels:   1,
+		SampleRate: 8000,
+	}, samples)
+	r, err := NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := r.BitDepth(); got != 8 {
+		t.Errorf("BitDepth() = %d, want 8", got)
+	}
+	got, err := ReadFull8PCM(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The bottom byte is truncated away, and the rest offset to be
+	// centred on 128.
+	want := [][]byte{{128, 255, 0, 129, 129, 127, 127}}
+	if d := cmp.Diff(got, want); d != "" {
+		t.Errorf("samples mismatch (-got, +want):\n%v", d)
+	}
+}
+
 func diff(t *testing.T, got, want []byte) {
 	t.Helper()
 	// Double check the initial RIFF chunk directly, mostly to
@@ -288,3 +352,2224 @@ func diff(t *testing.T, got, want []byte) {
 		t.Errorf("Data difference (-got, +want):\n%v", d)
 	}
 }
+
+func TestChainReaders(t *testing.T) {
+	raw, err := os.ReadFile("../testdata/kick.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	open := func() *Reader {
+		t.Helper()
+		r, err := NewReader(bytes.NewReader(raw))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+	want, err := ReadFull16PCM(open())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := ChainReaders(open(), open())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := r.Samples(), 2*len(want[0]); got != want {
+		t.Errorf("Samples() = %d, want %d", got, want)
+	}
+	got, err := ReadFull16PCM(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for c := range want {
+		want[c] = slices.Concat(want[c], want[c])
+	}
+	if d := cmp.Diff(got, want); d != "" {
+		t.Errorf("chained samples mismatch (-got, +want):\n%v", d)
+	}
+}
+
+// writeRIFF writes a RIFF file with the given form and chunks, returning the
+// raw bytes.
+func writeRIFF(t *testing.T, form string, chunks ...*riff.Chunk) []byte {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.riff")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := riff.NewWriter(f, form)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range chunks {
+		if err := w.WriteChunk(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+// rawChunk makes a chunk holding data.
+func rawChunk(id string, data []byte) *riff.Chunk {
+	return &riff.Chunk{
+		Identifier: id,
+		Size:       int64(len(data)),
+		Reader:     bytes.NewReader(data),
+	}
+}
+
+// pcm16Fmt is the body of a fmt chunk for mono, 16 bit, 44.1kHz PCM.
+func pcm16Fmt() []byte {
+	return cat(
+		uint16le(uint16(PCM)),
+		uint16le(1),
+		uint32le(44100),
+		uint32le(44100*2),
+		uint16le(2),
+		uint16le(16),
+	)
+}
+
+func TestRawChunks(t *testing.T) {
+	type chunk struct {
+		ID   string
+		Data []byte
+	}
+	want := []chunk{
+		{"data", []byte{1, 2, 3, 4, 5, 6}},
+		{"LIST", []byte("some odd sized metadata")},
+		{"abcd", []byte{7, 8}},
+	}
+	chunks := []*riff.Chunk{rawChunk("fmt ", pcm16Fmt())}
+	for _, c := range want {
+		chunks = append(chunks, rawChunk(c.ID, c.Data))
+	}
+	raw := writeRIFF(t, "WAVE", chunks...)
+
+	r, err := NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []chunk
+	for c, err := range r.RawChunks() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if int64(len(data)) != c.Size {
+			t.Errorf("chunk %q: Size is %d, read %d bytes", c.Identifier, c.Size, len(data))
+		}
+		got = append(got, chunk{c.Identifier, data})
+	}
+	if d := cmp.Diff(got, want); d != "" {
+		t.Errorf("RawChunks() mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestWriteFmtAndFact(t *testing.T) {
+	for _, ff := range []FileFormat{
+		{Format: IEEEFloat, BitDepth: 32, Channels: 2, SampleRate: 48000},
+		{Format: ALaw, BitDepth: 8, Channels: 2, SampleRate: 8000},
+		{Format: MuLaw, BitDepth: 8, Channels: 2, SampleRate: 8000},
+	} {
+		t.Run(ff.Format.String(), func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "test.wav")
+			f, err := os.Create(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w, err := NewWriter(f, ff)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// 3 stereo samples, the values don't matter.
+			if _, err := w.Write(make([]byte, 3*2*ff.BitDepth/8)); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if err := f.Close(); err != nil {
+				t.Fatal(err)
+			}
+			raw, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr, err := riff.NewReader(bytes.NewReader(raw))
+			if err != nil {
+				t.Fatal(err)
+			}
+			sizes := make(map[string]int64)
+			var fact []byte
+			for {
+				c, err := rr.ReadChunk()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				sizes[c.Identifier] = c.Size
+				if c.Identifier == "fact" {
+					if fact, err = io.ReadAll(c); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+			if got := sizes["fmt "]; got != 18 {
+				t.Errorf("fmt chunk is %d bytes, want 18", got)
+			}
+			if fact == nil {
+				t.Fatal("no fact chunk")
+			}
+			if d := cmp.Diff(fact, uint32le(3)); d != "" {
+				t.Errorf("fact chunk mismatch (-got, +want):\n%v", d)
+			}
+
+			r, err := NewReader(bytes.NewReader(raw))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !r.hasFact {
+				t.Error("Reader didn't find the fact chunk")
+			}
+			if got := r.Samples(); got != 3 {
+				t.Errorf("Samples() = %d, want 3", got)
+			}
+		})
+	}
+}
+
+func TestRoundTrip32Float(t *testing.T) {
+	want := [][]float32{
+		{0, 0.5, -0.5, 0.25, 1},
+		{-1, 0.125, -0.75, 0.001, 0},
+	}
+	path := filepath.Join(t.TempDir(), "float.wav")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewWriter(f, FileFormat{
+		Format:     IEEEFloat,
+		BitDepth:   32,
+		Channels:   2,
+		SampleRate: 48000,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write32Float(want); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadFull32Float(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff(got, want); d != "" {
+		t.Errorf("Read32Float mismatch (-got, +want):\n%v", d)
+	}
+
+	r, err = NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got64, err := ReadFull64Float(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want64 := makeSlices[float64](2, len(want[0]))
+	for c := range want {
+		for i, s := range want[c] {
+			want64[c][i] = float64(s)
+		}
+	}
+	if d := cmp.Diff(got64, want64); d != "" {
+		t.Errorf("Read64Float mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestWrite64Float(t *testing.T) {
+	samples := [][]float64{
+		{0, 0.5, -0.5, 0.25, 0.999},
+		{-1, 0.125, -0.75, 0.001, 0},
+	}
+	for _, c := range []struct {
+		format    Format
+		bitDepth  int
+		tolerance float64
+	}{
+		{PCM, 8, 1.0 / 64},
+		{PCM, 16, 1.0 / (1 << 14)},
+		{PCM, 24, 1.0 / (1 << 22)},
+		{IEEEFloat, 32, 1e-7},
+		{IEEEFloat, 64, 0},
+	} {
+		t.Run(fmt.Sprintf("%v %d", c.format, c.bitDepth), func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "test.wav")
+			f, err := os.Create(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w, err := NewWriter(f, FileFormat{
+				Format:     c.format,
+				BitDepth:   c.bitDepth,
+				Channels:   2,
+				SampleRate: 48000,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := w.Write64Float(samples); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if err := f.Close(); err != nil {
+				t.Fatal(err)
+			}
+			raw, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r, err := NewReader(bytes.NewReader(raw))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := ReadFull64Float(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d := cmp.Diff(got, samples, cmpopts.EquateApprox(0, c.tolerance)); d != "" {
+				t.Errorf("samples mismatch (-got, +want):\n%v", d)
+			}
+		})
+	}
+}
+
+func TestRoundTrip24PCM(t *testing.T) {
+	samples := [][]int32{
+		{0, 1<<23 - 1, -1 << 23, 123456, -654321},
+		{-1, 1, 1 << 22, 1 << 24, -1 << 24},
+	}
+	path := filepath.Join(t.TempDir(), "test.wav")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewWriter(f, FileFormat{
+		Format:     PCM,
+		BitDepth:   24,
+		Channels:   2,
+		SampleRate: 96000,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write24PCM(samples); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadFull24PCM(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The last two samples are out of range, so get clipped.
+	want := [][]int32{
+		{0, 1<<23 - 1, -1 << 23, 123456, -654321},
+		{-1, 1, 1 << 22, 1<<23 - 1, -1 << 23},
+	}
+	if d := cmp.Diff(got, want); d != "" {
+		t.Errorf("samples mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestWriteFloatUnsupported(t *testing.T) {
+	w, err := NewWriter(&testio.DiscardSeeker{}, FileFormat{
+		Format:     PCM,
+		BitDepth:   32,
+		Channels:   1,
+		SampleRate: 48000,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write64Float([][]float64{{0.5}}); err == nil {
+		t.Error("Write64Float into 32 bit PCM: expected error")
+	}
+	if _, err := w.Write32Float([][]float32{{0.5}}); err == nil {
+		t.Error("Write32Float into 32 bit PCM: expected error")
+	}
+}
+
+func TestReadFullComplex128(t *testing.T) {
+	raw, err := os.ReadFile("../testdata/kick.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := r.ReadFullComplex128()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err = NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := ReadFull64Float(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d channels, want %d", len(got), len(want))
+	}
+	for c := range want {
+		if len(got[c]) != len(want[c]) {
+			t.Fatalf("channel %d: got %d samples, want %d", c, len(got[c]), len(want[c]))
+		}
+		for i := range want[c] {
+			if got[c][i] != complex(want[c][i], 0) {
+				t.Fatalf("channel %d, sample %d: got %v, want %v", c, i, got[c][i], complex(want[c][i], 0))
+			}
+		}
+	}
+}
+
+func TestFileFormatChunk(t *testing.T) {
+	for _, c := range []struct {
+		ff      FileFormat
+		want    fmtChunk
+		wantErr bool
+	}{{
+		ff: FileFormat{Format: PCM, BitDepth: 24, Channels: 2, SampleRate: 48000},
+		want: fmtChunk{
+			format:             Extensible,
+			channels:           2,
+			sampleRate:         48000,
+			dataRate:           48000 * 6,
+			blockAlign:         6,
+			bitsPerSample:      24,
+			validBitsPerSample: 24,
+			channelMask:        0x3,
+			subFormat:          PCM,
+		},
+	}, {
+		ff: FileFormat{Format: PCM, BitDepth: 20, Channels: 2, SampleRate: 48000},
+		want: fmtChunk{
+			format:             Extensible,
+			channels:           2,
+			sampleRate:         48000,
+			dataRate:           48000 * 6,
+			blockAlign:         6,
+			bitsPerSample:      24,
+			validBitsPerSample: 20,
+			channelMask:        0x3,
+			subFormat:          PCM,
+		},
+	}, {
+		ff: FileFormat{Format: PCM, BitDepth: 16, Channels: 2, SampleRate: 44100},
+		want: fmtChunk{
+			format:        PCM,
+			channels:      2,
+			sampleRate:    44100,
+			dataRate:      44100 * 4,
+			blockAlign:    4,
+			bitsPerSample: 16,
+		},
+	}, {
+		ff: FileFormat{Format: IEEEFloat, BitDepth: 32, Channels: 6, SampleRate: 48000},
+		want: fmtChunk{
+			format:             Extensible,
+			channels:           6,
+			sampleRate:         48000,
+			dataRate:           48000 * 24,
+			blockAlign:         24,
+			bitsPerSample:      32,
+			validBitsPerSample: 32,
+			channelMask:        0x3F,
+			subFormat:          IEEEFloat,
+		},
+	}, {
+		ff: FileFormat{Format: MuLaw, BitDepth: 8, Channels: 1, SampleRate: 8000, ForceExtensible: true},
+		want: fmtChunk{
+			format:             Extensible,
+			channels:           1,
+			sampleRate:         8000,
+			dataRate:           8000,
+			blockAlign:         1,
+			bitsPerSample:      8,
+			validBitsPerSample: 8,
+			channelMask:        0x4,
+			subFormat:          MuLaw,
+		},
+	}, {
+		ff: FileFormat{Format: PCM, BitDepth: 12, Channels: 1, SampleRate: 8000},
+		want: fmtChunk{
+			format:        PCM,
+			channels:      1,
+			sampleRate:    8000,
+			dataRate:      8000 * 2,
+			blockAlign:    2,
+			bitsPerSample: 12,
+		},
+	}, {
+		ff:      FileFormat{Format: PCM, BitDepth: 0, Channels: 1, SampleRate: 8000},
+		wantErr: true,
+	}, {
+		ff:      FileFormat{Format: PCM, BitDepth: 40, Channels: 1, SampleRate: 8000},
+		wantErr: true,
+	}, {
+		ff:      FileFormat{Format: IEEEFloat, BitDepth: 24, Channels: 1, SampleRate: 8000},
+		wantErr: true,
+	}, {
+		ff:      FileFormat{Format: MuLaw, BitDepth: 16, Channels: 1, SampleRate: 8000},
+		wantErr: true,
+	}, {
+		ff:      FileFormat{Format: Extensible, BitDepth: 16, Channels: 1, SampleRate: 8000},
+		wantErr: true,
+	}} {
+		got, err := c.ff.chunk()
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("%+v.chunk(): expected error", c.ff)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%+v.chunk(): %v", c.ff, err)
+			continue
+		}
+		if d := cmp.Diff(got, c.want, cmp.AllowUnexported(fmtChunk{})); d != "" {
+			t.Errorf("%+v.chunk() mismatch (-got, +want):\n%v", c.ff, d)
+		}
+	}
+}
+
+func TestWriteExtensible(t *testing.T) {
+	ff := FileFormat{Format: PCM, BitDepth: 24, Channels: 6, SampleRate: 48000}
+	want := makeSlices[int32](6, 10)
+	for c := range want {
+		for i := range want[c] {
+			want[c][i] = int32((c*100 + i) * 1000)
+		}
+	}
+	path := filepath.Join(t.TempDir(), "surround.wav")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w, err := NewWriter(f, ff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write24PCM(want); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.fmt.format != Extensible || r.fmt.subFormat != PCM {
+		t.Errorf("format %v with subformat %v, want %v with subformat %v", r.fmt.format, r.fmt.subFormat, Extensible, PCM)
+	}
+	if got := r.ChannelMask(); got != 0x3F {
+		t.Errorf("ChannelMask() = %#x, want 0x3f", got)
+	}
+	got, err := ReadFull24PCM(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff(got, want); d != "" {
+		t.Errorf("samples mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestWriteBadSamples(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "test.wav"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w, err := NewWriter(f, FileFormat{
+		Format:     PCM,
+		BitDepth:   16,
+		Channels:   2,
+		SampleRate: 44100,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		name    string
+		samples [][]int16
+	}{{
+		name:    "too few channels",
+		samples: [][]int16{{1, 2, 3}},
+	}, {
+		name:    "too many channels",
+		samples: [][]int16{{1}, {2}, {3}},
+	}, {
+		name:    "empty channel",
+		samples: [][]int16{{1, 2, 3}, {}},
+	}, {
+		name:    "mismatched lengths",
+		samples: [][]int16{{1, 2, 3}, {1, 2}},
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			if _, err := w.Write16PCM(c.samples); err == nil {
+				t.Errorf("Write16PCM(%v): expected error", c.samples)
+			}
+		})
+	}
+}
+
+// badSeeker is an in-memory io.WriteSeeker. Once broken is set, it fails any
+// seek relative to the current position, other than asking where it is. If
+// failWrite is set, it fails the next write.
+type badSeeker struct {
+	buf       []byte
+	pos       int64
+	broken    bool
+	failWrite bool
+}
+
+var (
+	errBadSeek  = errors.New("bad seek")
+	errBadWrite = errors.New("bad write")
+)
+
+func (b *badSeeker) Write(p []byte) (int, error) {
+	if b.failWrite {
+		b.failWrite = false
+		return 0, errBadWrite
+	}
+	if end := b.pos + int64(len(p)); end > int64(len(b.buf)) {
+		b.buf = append(b.buf, make([]byte, end-int64(len(b.buf)))...)
+	}
+	n := copy(b.buf[b.pos:], p)
+	b.pos += int64(n)
+	return n, nil
+}
+
+func (b *badSeeker) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+		b.pos = offset
+	case io.SeekCurrent:
+		if offset != 0 && b.broken {
+			return 0, errBadSeek
+		}
+		b.pos += offset
+	case io.SeekEnd:
+		b.pos = int64(len(b.buf)) + offset
+	}
+	return b.pos, nil
+}
+
+func TestCloseFinishesRIFFAfterError(t *testing.T) {
+	bs := &badSeeker{}
+	w, err := NewWriter(bs, FileFormat{
+		Format:     PCM,
+		BitDepth:   16,
+		Channels:   1,
+		SampleRate: 44100,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write16PCM([][]int16{{1, 2, 3}}); err != nil {
+		t.Fatal(err)
+	}
+	// The data chunk seeks backwards to write its size, which fails.
+	bs.broken = true
+	if err := w.Close(); !errors.Is(err, errBadSeek) {
+		t.Errorf("Close() = %v, want %v", err, errBadSeek)
+	}
+	if w.dc != nil {
+		t.Error("data chunk writer still set after Close")
+	}
+	// The RIFF size should have been written anyway, although it can't
+	// include the unfinished data chunk.
+	if got := binary.LittleEndian.Uint32(bs.buf[4:]); got == 0 {
+		t.Error("RIFF size not written")
+	}
+}
+
+func TestCloseFinishesRIFFAfterFlushError(t *testing.T) {
+	bs := &badSeeker{}
+	w, err := NewWriter(bs, FileFormat{
+		Format:     PCM,
+		BitDepth:   16,
+		Channels:   1,
+		SampleRate: 44100,
+	}, WithFadeOut(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write16PCM([][]int16{{1, 2, 3, 4, 5, 6, 7, 8}}); err != nil {
+		t.Fatal(err)
+	}
+	// The faded out samples are held back until Close, and writing them
+	// fails.
+	bs.failWrite = true
+	if err := w.Close(); !errors.Is(err, errBadWrite) {
+		t.Errorf("Close() = %v, want %v", err, errBadWrite)
+	}
+	if w.dc != nil {
+		t.Error("data chunk writer still set after Close")
+	}
+	if got, want := binary.LittleEndian.Uint32(bs.buf[4:]), uint32(len(bs.buf)-8); got != want {
+		t.Errorf("RIFF size = %d, want %d", got, want)
+	}
+	// Closing again shouldn't write the held back samples.
+	size := len(bs.buf)
+	if err := w.Close(); err == nil {
+		t.Error("second Close: expected error")
+	}
+	if len(bs.buf) != size {
+		t.Errorf("second Close wrote %d more bytes", len(bs.buf)-size)
+	}
+}
+
+func TestWriteOverflow(t *testing.T) {
+	ff := FileFormat{
+		Format:     PCM,
+		BitDepth:   16,
+		Channels:   2,
+		SampleRate: 44100,
+	}
+	// (2^32 - 1 - 36 bytes of headers) / 4 bytes per sample.
+	const wantMax = 1073741814
+	if got := ff.MaxSamples(); got != wantMax {
+		t.Fatalf("MaxSamples() = %d, want %d", got, wantMax)
+	}
+
+	w, err := NewWriter(&testio.DiscardSeeker{}, ff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Pretend we've already written almost everything, rather than
+	// actually writing 4GB.
+	w.dataBytes = (wantMax - 1) * 4
+	samples := [][]int16{{1}, {2}}
+	if _, err := w.Write16PCM(samples); err != nil {
+		t.Fatalf("writing the last sample: %v", err)
+	}
+	if _, err := w.Write16PCM(samples); !errors.Is(err, ErrDataChunkOverflow) {
+		t.Fatalf("writing past the end: got error %v, want %v", err, ErrDataChunkOverflow)
+	}
+}
+
+func TestWriteOverflowTrailingChunks(t *testing.T) {
+	ff := FileFormat{
+		Format:     PCM,
+		BitDepth:   16,
+		Channels:   2,
+		SampleRate: 44100,
+	}
+	w, err := NewWriter(&testio.DiscardSeeker{}, ff, WithIntegrityChunk())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The data fills the file exactly, leaving no room for the sum chunk.
+	w.dataBytes = (int64(ff.MaxSamples()) - 1) * 4
+	if _, err := w.Write16PCM([][]int16{{1}, {2}}); err != nil {
+		t.Fatalf("writing the last sample: %v", err)
+	}
+	if err := w.Close(); !errors.Is(err, ErrDataChunkOverflow) {
+		t.Errorf("Close: got error %v, want %v", err, ErrDataChunkOverflow)
+	}
+}
+
+func TestWriteSamplesBatches(t *testing.T) {
+	// Enough samples to need a few batches, and a bit more.
+	const n = 3*writeBatchSize/4 + 7
+	samples := makeSlices[int16](2, n)
+	var want []byte
+	for i := range n {
+		for c := range samples {
+			samples[c][i] = int16(i*(c+1) - n)
+			want = binary.LittleEndian.AppendUint16(want, uint16(samples[c][i]))
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "batches.wav")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewWriter(f, FileFormat{
+		Format:     PCM,
+		BitDepth:   16,
+		Channels:   2,
+		SampleRate: 44100,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	written, err := w.Write16PCM(samples)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if written != len(want) {
+		t.Errorf("Write16PCM wrote %d bytes, want %d", written, len(want))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("data mismatch: got %d bytes, want %d", len(got), len(want))
+	}
+}
+
+func BenchmarkWrite16PCM(b *testing.B) {
+	// 10 seconds of stereo, 44.1kHz audio is about 1.7MB. The memory
+	// allocated per op should be much less than that.
+	samples := makeSlices[int16](2, 441000)
+	w, err := NewWriter(&testio.DiscardSeeker{}, FileFormat{
+		Format:     PCM,
+		BitDepth:   16,
+		Channels:   2,
+		SampleRate: 44100,
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.SetBytes(int64(2 * 2 * len(samples[0])))
+	for b.Loop() {
+		if _, err := w.Write16PCM(samples); err != nil {
+			b.Fatal(err)
+		}
+		// Don't run into the limit on the size of the file.
+		w.dataBytes = 0
+	}
+}
+
+// int24le encodes the low 24 bits of i, little-endian.
+func int24le(i int32) []byte {
+	return []byte{byte(i), byte(i >> 8), byte(i >> 16)}
+}
+
+func TestReadPlainMultichannel24PCM(t *testing.T) {
+	// The spec says this should be Extensible, but plenty of encoders
+	// write it as plain PCM anyway.
+	const channels = 6
+	fc := cat(
+		uint16le(uint16(PCM)),
+		uint16le(channels),
+		uint32le(48000),
+		uint32le(48000*channels*3),
+		uint16le(channels*3),
+		uint16le(24),
+	)
+	want := [][]int32{
+		{0, 1 << 22, -1 << 22},
+		{1<<23 - 1, -1 << 23, -1},
+		{256, -256, 12345 << 8},
+		{-5 << 16, 5 << 16, 0},
+		{1 << 8, 2 << 8, 3 << 8},
+		{-1 << 8, -2 << 8, -3 << 8},
+	}
+	var data []byte
+	for i := range want[0] {
+		for c := range want {
+			data = append(data, int24le(want[c][i])...)
+		}
+	}
+	raw := writeRIFF(t, "WAVE", rawChunk("fmt ", fc), rawChunk("data", data))
+
+	r, err := NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := r.Format(); got != PCM {
+		t.Errorf("Format() = %v, want %v", got, PCM)
+	}
+	if got := r.Channels(); got != channels {
+		t.Errorf("Channels() = %d, want %d", got, channels)
+	}
+	if got := r.BitDepth(); got != 24 {
+		t.Errorf("BitDepth() = %d, want 24", got)
+	}
+	if got := r.Samples(); got != len(want[0]) {
+		t.Errorf("Samples() = %d, want %d", got, len(want[0]))
+	}
+
+	got16, err := ReadFull16PCM(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want16 := makeSlices[int16](channels, len(want[0]))
+	for c := range want {
+		for i, s := range want[c] {
+			want16[c][i] = int16(s >> 8)
+		}
+	}
+	if d := cmp.Diff(got16, want16); d != "" {
+		t.Errorf("Read16PCM mismatch (-got, +want):\n%v", d)
+	}
+
+	r, err = NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got64, err := ReadFull64Float(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for c := range want {
+		for i, s := range want[c] {
+			if w := float64(s) / (1<<23 - 1); math.Abs(got64[c][i]-w) > 1e-9 {
+				t.Errorf("Read64Float channel %d sample %d: got %v, want %v", c, i, got64[c][i], w)
+			}
+		}
+	}
+}
+
+func TestRead24PCM(t *testing.T) {
+	pcmFmt := func(bits int) []byte {
+		size := (bits + 7) / 8
+		return cat(
+			uint16le(uint16(PCM)),
+			uint16le(1),
+			uint32le(44100),
+			uint32le(uint32(44100*size)),
+			uint16le(uint16(size)),
+			uint16le(uint16(bits)),
+		)
+	}
+	for _, c := range []struct {
+		name string
+		fmt  []byte
+		data []byte
+		want []int32
+	}{{
+		name: "8 bit",
+		fmt:  pcmFmt(8),
+		data: []byte{128, 255, 0, 129},
+		want: []int32{0, 127 << 16, -128 << 16, 1 << 16},
+	}, {
+		name: "16 bit",
+		fmt:  pcmFmt(16),
+		data: cat(uint16le(0), uint16le(0x7fff), uint16le(0x8000), uint16le(0xffff)),
+		want: []int32{0, 0x7fff << 8, -0x8000 << 8, -1 << 8},
+	}, {
+		name: "24 bit",
+		fmt:  pcmFmt(24),
+		data: cat(int24le(0), int24le(1<<23-1), int24le(-1<<23), int24le(-1), int24le(-123456)),
+		want: []int32{0, 1<<23 - 1, -1 << 23, -1, -123456},
+	}, {
+		name: "32 bit",
+		fmt:  pcmFmt(32),
+		data: cat(uint32le(0x7fffffff), uint32le(0x80000000), uint32le(0x000001ff)),
+		want: []int32{1<<23 - 1, -1 << 23, 1},
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			raw := writeRIFF(t, "WAVE", rawChunk("fmt ", c.fmt), rawChunk("data", c.data))
+			r, err := NewReader(bytes.NewReader(raw))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := ReadFull24PCM(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d := cmp.Diff(got, [][]int32{c.want}); d != "" {
+				t.Errorf("ReadFull24PCM mismatch (-got, +want):\n%v", d)
+			}
+		})
+	}
+}
+
+// write16PCM writes samples to a new wav file with format ff, returning the raw
+// bytes of the file.
+func write16PCM(t *testing.T, ff FileFormat, samples [][]int16) []byte {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.wav")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewWriter(f, ff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write16PCM(samples); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestSeek(t *testing.T) {
+	const n = 1000
+	samples := makeSlices[int16](2, n)
+	for i := range n {
+		samples[0][i] = int16(i)
+		samples[1][i] = int16(-i)
+	}
+	raw := write16PCM(t, FileFormat{
+		Format:     PCM,
+		BitDepth:   16,
+		Channels:   2,
+		SampleRate: 44100,
+	}, samples)
+	r, err := NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := makeSlices[int16](2, 10)
+	for _, offset := range []int{500, 3, 0, n - 10} {
+		if err := r.Seek(offset); err != nil {
+			t.Fatalf("Seek(%d): %v", offset, err)
+		}
+		if _, err := r.Read16PCM(got); err != nil {
+			t.Fatal(err)
+		}
+		want := [][]int16{samples[0][offset : offset+10], samples[1][offset : offset+10]}
+		if d := cmp.Diff(got, want); d != "" {
+			t.Errorf("after Seek(%d): mismatch (-got, +want):\n%v", offset, d)
+		}
+	}
+
+	// Seeking near the end should still stop at the end of the data.
+	if err := r.Seek(n - 5); err != nil {
+		t.Fatal(err)
+	}
+	read, err := r.Read16PCM(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read != 5 {
+		t.Errorf("Read16PCM after Seek(%d) read %d samples, want 5", n-5, read)
+	}
+	if err := r.Seek(n); err != nil {
+		t.Errorf("Seek(Samples()): %v", err)
+	}
+	if _, err := r.Read16PCM(got); err != io.EOF {
+		t.Errorf("Read16PCM at the end: got %v, want EOF", err)
+	}
+	if err := r.Seek(n + 1); err == nil {
+		t.Error("Seek past the end: expected error")
+	}
+	if err := r.Seek(-1); err == nil {
+		t.Error("Seek(-1): expected error")
+	}
+
+	// A plain io.Reader can't seek.
+	r, err = NewReader(bytes.NewBuffer(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Seek(0); err == nil {
+		t.Error("Seek on a non-seekable reader: expected error")
+	}
+}
+
+func TestUnknownDataSize(t *testing.T) {
+	// More than fits in a single block, to check ReadFull16PCM keeps going.
+	const n = 5000
+	samples := makeSlices[int16](2, n)
+	for i := range n {
+		samples[0][i] = int16(i)
+		samples[1][i] = int16(-i)
+	}
+	raw := write16PCM(t, FileFormat{
+		Format:     PCM,
+		BitDepth:   16,
+		Channels:   2,
+		SampleRate: 44100,
+	}, samples)
+	// Pretend a streaming encoder wrote it and didn't go back to fill in the
+	// size of the data chunk.
+	i := bytes.Index(raw, []byte("data"))
+	copy(raw[i+4:], uint32le(0xFFFFFFFF))
+
+	r, err := NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := r.Samples(); got != -1 {
+		t.Errorf("Samples() = %d, want -1", got)
+	}
+	got, err := ReadFull16PCM(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff(got, samples); d != "" {
+		t.Errorf("ReadFull16PCM() mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestChannel(t *testing.T) {
+	const n = 1001
+	samples := makeSlices[int16](2, n)
+	for i := range n {
+		samples[0][i] = int16(i)
+		samples[1][i] = int16(-7 * i)
+	}
+	raw := write16PCM(t, FileFormat{
+		Format:     PCM,
+		BitDepth:   16,
+		Channels:   2,
+		SampleRate: 44100,
+	}, samples)
+
+	r, err := NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Channel(2); err == nil {
+		t.Error("Channel(2) of a stereo file: expected error")
+	}
+	mono, err := r.Channel(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := mono.Channels(); got != 1 {
+		t.Errorf("Channels() = %d, want 1", got)
+	}
+	if got := mono.Samples(); got != n {
+		t.Errorf("Samples() = %d, want %d", got, n)
+	}
+	// Read in awkward sized pieces, to exercise the buffering.
+	var got []int16
+	buf := [][]int16{make([]int16, 77)}
+	for {
+		k, err := mono.Read16PCM(buf)
+		got = append(got, buf[0][:k]...)
+		if err == io.EOF || k == 0 {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d := cmp.Diff(got, samples[1]); d != "" {
+		t.Errorf("channel 1 mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestRF64Reservation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reserved.wav")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewWriter(f, FileFormat{
+		Format:     PCM,
+		BitDepth:   16,
+		Channels:   1,
+		SampleRate: 44100,
+	}, WithRF64Reservation())
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples := [][]int16{{1, -1, 2, -2}}
+	if _, err := w.Write16PCM(samples); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr, err := riff.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := rr.ReadChunk()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Identifier != "JUNK" || c.Size != 28 {
+		t.Errorf("first chunk: got %q with size %d, want %q with size 28", c.Identifier, c.Size, "JUNK")
+	}
+
+	// The file should still be readable.
+	r, err := NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadFull16PCM(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff(got, samples); d != "" {
+		t.Errorf("samples mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestReadFloat32Into(t *testing.T) {
+	const n = 999
+	samples := makeSlices[int16](2, n)
+	for i := range n {
+		samples[0][i] = int16(i * 31)
+		samples[1][i] = int16(-i * 17)
+	}
+	raw := write16PCM(t, FileFormat{
+		Format:     PCM,
+		BitDepth:   16,
+		Channels:   2,
+		SampleRate: 44100,
+	}, samples)
+	r, err := NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.ReadFloat32Into(make([]float32, 3)); err == nil {
+		t.Error("ReadFloat32Into(3 samples) of a stereo file: expected error")
+	}
+	var (
+		got = makeSlices[float32](2, 0)
+		buf = make([]float32, 2*100)
+	)
+	for {
+		frames, err := r.ReadFloat32Into(buf)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range frames {
+			got[0] = append(got[0], buf[2*i])
+			got[1] = append(got[1], buf[2*i+1])
+		}
+	}
+	want := makeSlices[float32](2, n)
+	for c := range samples {
+		for i, s := range samples[c] {
+			want[c][i] = from16PCMToFloat32(s)
+		}
+	}
+	if d := cmp.Diff(got, want, cmpopts.EquateApprox(1e-6, 0)); d != "" {
+		t.Errorf("samples mismatch (-got, +want):\n%v", d)
+	}
+}
+
+// silentReader returns a Reader for an endless stereo, 16 bit file.
+func silentReader() *Reader {
+	return &Reader{
+		fmt: fmtChunk{
+			format:        PCM,
+			channels:      2,
+			sampleRate:    44100,
+			dataRate:      44100 * 2 * 2,
+			blockAlign:    2 * 2,
+			bitsPerSample: 16,
+		},
+		data: silence(0),
+	}
+}
+
+func TestReadBigEndian16(t *testing.T) {
+	const n = 999
+	samples := makeSlices[int16](2, n)
+	for i := range n {
+		samples[0][i] = int16(i * 31)
+		samples[1][i] = int16(-i * 17)
+	}
+	raw := write16PCM(t, FileFormat{
+		Format:     PCM,
+		BitDepth:   16,
+		Channels:   2,
+		SampleRate: 44100,
+	}, samples)
+	r, err := NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.ReadBigEndian16(make([]byte, 6)); err == nil {
+		t.Error("ReadBigEndian16(6 bytes) of a stereo file: expected error")
+	}
+	var (
+		got []byte
+		buf = make([]byte, 4*100)
+	)
+	for {
+		n, err := r.ReadBigEndian16(buf)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, buf[:n]...)
+	}
+
+	r, err = NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := ReadFull16PCM(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want []byte
+	for i := range decoded[0] {
+		for c := range decoded {
+			s := decoded[c][i]
+			want = append(want, byte(s>>8), byte(s))
+		}
+	}
+	if d := cmp.Diff(got, want); d != "" {
+		t.Errorf("ReadBigEndian16 mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestPipeFloat32LE(t *testing.T) {
+	// More than one buffer's worth.
+	const n = pipeFrames*2 + 123
+	samples := makeSlices[int16](2, n)
+	for i := range n {
+		samples[0][i] = int16(i * 7)
+		samples[1][i] = int16(-i * 3)
+	}
+	raw := write16PCM(t, FileFormat{
+		Format:     PCM,
+		BitDepth:   16,
+		Channels:   2,
+		SampleRate: 44100,
+	}, samples)
+	r, err := NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	written, err := r.PipeFloat32LE(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := int64(2 * 4 * n); written != want || int64(buf.Len()) != want {
+		t.Errorf("PipeFloat32LE wrote %d bytes (buffer has %d), want %d", written, buf.Len(), want)
+	}
+	got := makeSlices[float32](2, 0)
+	for b := buf.Bytes(); len(b) >= 8; b = b[8:] {
+		l, _ := nextFloat32(b)
+		r, _ := nextFloat32(b[4:])
+		got[0] = append(got[0], l)
+		got[1] = append(got[1], r)
+	}
+	want := makeSlices[float32](2, n)
+	for c := range samples {
+		for i, s := range samples[c] {
+			want[c][i] = from16PCMToFloat32(s)
+		}
+	}
+	if d := cmp.Diff(got, want, cmpopts.EquateApprox(1e-6, 0)); d != "" {
+		t.Errorf("samples mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestReadFloat32IntoAllocs(t *testing.T) {
+	r := silentReader()
+	buf := make([]float32, 2*512)
+	allocs := testing.AllocsPerRun(100, func() {
+		if _, err := r.ReadFloat32Into(buf); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("ReadFloat32Into: %v allocations per run, want 0", allocs)
+	}
+}
+
+func BenchmarkReadFloat32Into(b *testing.B) {
+	r := silentReader()
+	buf := make([]float32, 2*512)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(buf) * 2))
+	for b.Loop() {
+		if _, err := r.ReadFloat32Into(buf); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestStdFormat(t *testing.T) {
+	raw := write16PCM(t, FileFormat{
+		Format:     PCM,
+		BitDepth:   16,
+		Channels:   3,
+		SampleRate: 22050,
+	}, [][]int16{{1}, {2}, {3}})
+	r, err := NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := AudioFormat{NumChannels: 3, SampleRate: 22050}
+	if got := r.StdFormat(); got != want {
+		t.Errorf("StdFormat() = %+v, want %+v", got, want)
+	}
+}
+
+func TestReadLeftJustified24In32(t *testing.T) {
+	fc := cat(
+		uint16le(uint16(Extensible)),
+		uint16le(2),
+		uint32le(48000),
+		uint32le(48000*2*4),
+		uint16le(2*4),
+		uint16le(32),
+		uint16le(22),
+		// Only 24 of the 32 bits are used.
+		uint16le(24),
+		uint32le(0x3),
+		mkSubformat(PCM),
+	)
+	want := [][]int32{
+		{0, 1<<23 - 1, -1 << 23, 12345},
+		{-1, 1, 1 << 22, -54321},
+	}
+	var data []byte
+	for i := range want[0] {
+		for c := range want {
+			// Left justified, the bottom byte is unused. Put some
+			// junk there to make sure it's ignored.
+			data = append(data, uint32le(uint32(want[c][i])<<8|0xAB)...)
+		}
+	}
+	raw := writeRIFF(t, "WAVE", rawChunk("fmt ", fc), rawChunk("data", data))
+
+	r, err := NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got32, err := ReadFull32Float(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for c := range want {
+		for i, s := range want[c] {
+			if w := float32(float64(s) / (1<<23 - 1)); got32[c][i] != w {
+				t.Errorf("channel %d sample %d: got %v, want %v", c, i, got32[c][i], w)
+			}
+		}
+	}
+
+	r, err = NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotFloat, err := ReadFull64Float(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for c := range want {
+		for i, s := range want[c] {
+			if w := float64(s) / (1<<23 - 1); math.Abs(gotFloat[c][i]-w) > 1e-12 {
+				t.Errorf("channel %d sample %d: got %v, want %v", c, i, gotFloat[c][i], w)
+			}
+		}
+	}
+}
+
+func TestReadExtensible20In24(t *testing.T) {
+	fc := cat(
+		uint16le(uint16(Extensible)),
+		uint16le(1),
+		uint32le(48000),
+		uint32le(48000*3),
+		uint16le(3),
+		uint16le(24),
+		uint16le(22),
+		// Only the top 20 of the 24 bits are used.
+		uint16le(20),
+		uint32le(0x4),
+		mkSubformat(PCM),
+	)
+	// Samples in 20 bits, and left justified in 24.
+	samples := []int32{0, 1<<19 - 1, -1 << 19, 12345, -54321}
+	var data []byte
+	for _, s := range samples {
+		// Put junk in the unused low bits to make sure it's
+		// ignored.
+		v := uint32(s)<<4 | 0xA
+		data = append(data, byte(v), byte(v>>8), byte(v>>16))
+	}
+	raw := writeRIFF(t, "WAVE", rawChunk("fmt ", fc), rawChunk("data", data))
+
+	r, err := NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got24 := makeSlices[int32](1, len(samples))
+	if _, err := r.Read24PCM(got24); err != nil {
+		t.Fatal(err)
+	}
+	want24 := make([]int32, len(samples))
+	for i, s := range samples {
+		want24[i] = s << 4
+	}
+	if d := cmp.Diff(got24, [][]int32{want24}); d != "" {
+		t.Errorf("Read24PCM mismatch (-got, +want):\n%v", d)
+	}
+
+	r, err = NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got16 := makeSlices[int16](1, len(samples))
+	if _, err := r.Read16PCM(got16); err != nil {
+		t.Fatal(err)
+	}
+	want16 := make([]int16, len(samples))
+	for i, s := range samples {
+		want16[i] = int16(s >> 4)
+	}
+	if d := cmp.Diff(got16, [][]int16{want16}); d != "" {
+		t.Errorf("Read16PCM mismatch (-got, +want):\n%v", d)
+	}
+
+	r, err = NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotFloat := makeSlices[float64](1, len(samples))
+	if _, err := r.Read64Float(gotFloat); err != nil {
+		t.Fatal(err)
+	}
+	for i, s := range want24 {
+		if w := float64(s) / (1<<23 - 1); gotFloat[0][i] != w {
+			t.Errorf("Read64Float sample %d: got %v, want %v", i, gotFloat[0][i], w)
+		}
+	}
+}
+
+func TestZeroBlockAlign(t *testing.T) {
+	fc := cat(
+		uint16le(uint16(PCM)),
+		uint16le(2),
+		uint32le(44100),
+		uint32le(44100*2*2),
+		uint16le(0),
+		uint16le(16),
+	)
+	want := [][]int16{{1, -2, 3}, {-4, 5, -6}}
+	var data []byte
+	for i := range want[0] {
+		for c := range want {
+			data = append(data, uint16le(uint16(want[c][i]))...)
+		}
+	}
+	raw := writeRIFF(t, "WAVE", rawChunk("fmt ", fc), rawChunk("data", data))
+
+	r, err := NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Warnings()) == 0 {
+		t.Error("Warnings() is empty, want a warning about the block size")
+	}
+	if got := r.Samples(); got != len(want[0]) {
+		t.Errorf("Samples() = %d, want %d", got, len(want[0]))
+	}
+	got, err := ReadFull16PCM(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff(got, want); d != "" {
+		t.Errorf("samples mismatch (-got, +want):\n%v", d)
+	}
+
+	r, err = NewReader(bytes.NewReader(write16PCM(t, FileFormat{
+		Format:     PCM,
+		BitDepth:   16,
+		Channels:   2,
+		SampleRate: 44100,
+	}, want)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := r.Warnings(); len(got) != 0 {
+		t.Errorf("Warnings() for a valid file = %q, want none", got)
+	}
+}
+
+func TestFmtChunkNotFirst(t *testing.T) {
+	raw := writeRIFF(t, "WAVE",
+		rawChunk("JUNK", make([]byte, 28)),
+		rawChunk("bext", []byte("broadcast wave")),
+		rawChunk("fmt ", pcm16Fmt()),
+		rawChunk("data", cat(uint16le(1), uint16le(2), uint16le(3))),
+	)
+	r, err := NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadFull16PCM(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff(got, [][]int16{{1, 2, 3}}); d != "" {
+		t.Errorf("samples mismatch (-got, +want):\n%v", d)
+	}
+	var ids []string
+	for mc, err := range r.AllMetadata() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, mc.ID)
+	}
+	if d := cmp.Diff(ids, []string{"bext"}); d != "" {
+		t.Errorf("metadata chunks mismatch (-got, +want):\n%v", d)
+	}
+
+	for _, c := range []struct {
+		name   string
+		chunks []*riff.Chunk
+	}{{
+		name: "data before fmt",
+		chunks: []*riff.Chunk{
+			rawChunk("data", cat(uint16le(1))),
+			rawChunk("fmt ", pcm16Fmt()),
+		},
+	}, {
+		name: "no fmt",
+		chunks: []*riff.Chunk{
+			rawChunk("bext", []byte("broadcast wave")),
+		},
+	}, {
+		name: "two fmt",
+		chunks: []*riff.Chunk{
+			rawChunk("fmt ", pcm16Fmt()),
+			rawChunk("fmt ", pcm16Fmt()),
+			rawChunk("data", cat(uint16le(1))),
+		},
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			if _, err := NewReader(bytes.NewReader(writeRIFF(t, "WAVE", c.chunks...))); err == nil {
+				t.Error("NewReader: expected error")
+			}
+		})
+	}
+}
+
+func TestReadUnsupportedFloatBitDepth(t *testing.T) {
+	fc := cat(
+		uint16le(uint16(IEEEFloat)),
+		uint16le(1),
+		uint32le(44100),
+		uint32le(44100*2),
+		uint16le(2),
+		uint16le(16),
+		uint16le(0),
+	)
+	raw := writeRIFF(t, "WAVE", rawChunk("fmt ", fc), rawChunk("data", make([]byte, 10)))
+	for _, c := range []struct {
+		name string
+		read func(*Reader) error
+	}{{
+		name: "Read32Float",
+		read: func(r *Reader) error {
+			_, err := ReadFull32Float(r)
+			return err
+		},
+	}, {
+		name: "Read64Float",
+		read: func(r *Reader) error {
+			_, err := ReadFull64Float(r)
+			return err
+		},
+	}, {
+		name: "ReadFloat32Into",
+		read: func(r *Reader) error {
+			_, err := r.ReadFloat32Into(make([]float32, 5))
+			return err
+		},
+	}, {
+		name: "Read16PCM",
+		read: func(r *Reader) error {
+			_, err := ReadFull16PCM(r)
+			return err
+		},
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			r, err := NewReader(bytes.NewReader(raw))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := c.read(r); err == nil {
+				t.Error("reading 16 bit float: expected error")
+			}
+		})
+	}
+}
+
+func TestValidBits(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		fmt      []byte
+		bitDepth int
+		valid    int
+	}{{
+		name:     "plain 16 bit",
+		fmt:      pcm16Fmt(),
+		bitDepth: 16,
+		valid:    16,
+	}, {
+		name: "24 in 32",
+		fmt: cat(
+			uint16le(uint16(Extensible)),
+			uint16le(2),
+			uint32le(48000),
+			uint32le(48000*2*4),
+			uint16le(2*4),
+			uint16le(32),
+			uint16le(22),
+			uint16le(24),
+			uint32le(0x3),
+			mkSubformat(PCM),
+		),
+		bitDepth: 32,
+		valid:    24,
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			raw := writeRIFF(t, "WAVE", rawChunk("fmt ", c.fmt), rawChunk("data", nil))
+			r, err := NewReader(bytes.NewReader(raw))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := r.BitDepth(); got != c.bitDepth {
+				t.Errorf("BitDepth() = %d, want %d", got, c.bitDepth)
+			}
+			if got := r.ValidBits(); got != c.valid {
+				t.Errorf("ValidBits() = %d, want %d", got, c.valid)
+			}
+		})
+	}
+}
+
+func TestSamples(t *testing.T) {
+	muLawFmt := cat(
+		uint16le(uint16(MuLaw)),
+		uint16le(1),
+		uint32le(8000),
+		uint32le(8000),
+		uint16le(1),
+		uint16le(8),
+		uint16le(0),
+	)
+	floatFmt := cat(
+		uint16le(uint16(IEEEFloat)),
+		uint16le(2),
+		uint32le(8000),
+		uint32le(8000*2*4),
+		uint16le(2*4),
+		uint16le(32),
+		uint16le(0),
+	)
+	adpcmFmt := cat(
+		uint16le(2), // Microsoft ADPCM
+		uint16le(1),
+		uint32le(8000),
+		uint32le(4096),
+		uint16le(256),
+		uint16le(4),
+		uint16le(0),
+	)
+	for _, c := range []struct {
+		name    string
+		chunks  []*riff.Chunk
+		want    int
+		wantErr bool
+	}{{
+		name: "PCM ignores fact",
+		chunks: []*riff.Chunk{
+			rawChunk("fmt ", pcm16Fmt()),
+			rawChunk("fact", uint32le(1)),
+			rawChunk("data", make([]byte, 10)),
+		},
+		want: 5,
+	}, {
+		name: "mu-law with fact",
+		chunks: []*riff.Chunk{
+			rawChunk("fmt ", muLawFmt),
+			rawChunk("fact", uint32le(7)),
+			rawChunk("data", make([]byte, 10)),
+		},
+		want: 7,
+	}, {
+		name: "mu-law with too big fact",
+		chunks: []*riff.Chunk{
+			rawChunk("fmt ", muLawFmt),
+			rawChunk("fact", uint32le(100)),
+			rawChunk("data", make([]byte, 10)),
+		},
+		want: 10,
+	}, {
+		name: "float without fact",
+		chunks: []*riff.Chunk{
+			rawChunk("fmt ", floatFmt),
+			rawChunk("data", make([]byte, 3*8)),
+		},
+		want: 3,
+	}, {
+		name: "variable rate",
+		chunks: []*riff.Chunk{
+			rawChunk("fmt ", adpcmFmt),
+			rawChunk("fact", uint32le(1000)),
+			rawChunk("data", make([]byte, 512)),
+		},
+		wantErr: true,
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			r, err := NewReader(bytes.NewReader(writeRIFF(t, "WAVE", c.chunks...)))
+			if err != nil {
+				if !c.wantErr {
+					t.Fatal(err)
+				}
+				return
+			}
+			if c.wantErr {
+				t.Fatalf("NewReader: expected error, Samples() = %d", r.Samples())
+			}
+			if got := r.Samples(); got != c.want {
+				t.Errorf("Samples() = %d, want %d", got, c.want)
+			}
+		})
+	}
+}
+
+// decodedBytes returns a function that decodes a whole file with read, and
+// returns the number of bytes of samples it got.
+func decodedBytes[T any](read func(*Reader) ([][]T, error)) func(*Reader) (int64, error) {
+	return func(r *Reader) (int64, error) {
+		data, err := read(r)
+		if err != nil {
+			return 0, err
+		}
+		var zero T
+		return int64(len(data)) * int64(len(data[0])) * int64(unsafe.Sizeof(zero)), nil
+	}
+}
+
+func TestDecodedSize(t *testing.T) {
+	raw, err := os.ReadFile("../testdata/kick.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		st   SampleType
+		read func(*Reader) (int64, error)
+	}{
+		{Sample8PCM, decodedBytes(ReadFull8PCM)},
+		{Sample16PCM, decodedBytes(ReadFull16PCM)},
+		{Sample24PCM, decodedBytes(ReadFull24PCM)},
+		{Sample32Float, decodedBytes(ReadFull32Float)},
+		{Sample64Float, decodedBytes(ReadFull64Float)},
+		{SampleComplex128, decodedBytes((*Reader).ReadFullComplex128)},
+	} {
+		r, err := NewReader(bytes.NewReader(raw))
+		if err != nil {
+			t.Fatal(err)
+		}
+		size := r.DecodedSize(c.st)
+		got, err := c.read(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if size != got {
+			t.Errorf("DecodedSize(%d) = %d, decoding allocated %d bytes", c.st, size, got)
+		}
+	}
+
+	r, err := NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := r.DecodedSize(SampleType(-1)); got != -1 {
+		t.Errorf("DecodedSize(-1) = %d, want -1", got)
+	}
+}
+
+func TestReadRaggedSlices(t *testing.T) {
+	samples := [][]int16{{1, 2, 3}, {4, 5, 6}}
+	raw := write16PCM(t, FileFormat{
+		Format:     PCM,
+		BitDepth:   16,
+		Channels:   2,
+		SampleRate: 44100,
+	}, samples)
+	r, err := NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := r.Read16PCM([][]int16{{0, 0}, {0}}); err == nil {
+		t.Errorf("Read16PCM with ragged slices = %d, expected error", n)
+	}
+	if n, err := r.Read32Float([][]float32{{0}, {0, 0}}); err == nil {
+		t.Errorf("Read32Float with ragged slices = %d, expected error", n)
+	}
+	// Nothing should have been read.
+	got, err := ReadFull16PCM(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff(got, samples); d != "" {
+		t.Errorf("ReadFull16PCM() after errors mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestReadTruncatedMidFrame(t *testing.T) {
+	raw, err := os.ReadFile("../testdata/kick.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	all, err := ReadFull16PCM(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Cut the file off one byte into a frame, part way through the audio.
+	const frames = 1000
+	start := bytes.Index(raw, []byte("data")) + 8
+	blockAlign := int(r.fmt.blockAlign)
+	truncated := raw[:start+frames*blockAlign+1]
+
+	r, err = NewReader(bytes.NewReader(truncated))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := makeSlices[int16](r.Channels(), r.Samples())
+	n, err := r.Read16PCM(got)
+	if err != io.ErrUnexpectedEOF {
+		t.Errorf("Read16PCM on a truncated file: got error %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+	if n != frames {
+		t.Fatalf("Read16PCM on a truncated file read %d frames, want %d", n, frames)
+	}
+	for c := range got {
+		if d := cmp.Diff(got[c][:n], all[c][:frames]); d != "" {
+			t.Errorf("channel %d mismatch (-got, +want):\n%v", c, d)
+		}
+	}
+}
+
+func TestInterleavedReadsTruncatedMidFrame(t *testing.T) {
+	samples := makeSlices[int16](2, 10)
+	for i := range samples[0] {
+		samples[0][i] = int16(i + 1)
+		samples[1][i] = int16(-i - 1)
+	}
+	raw := write16PCM(t, FileFormat{
+		Format:     PCM,
+		BitDepth:   16,
+		Channels:   2,
+		SampleRate: 44100,
+	}, samples)
+	// Cut the file off one byte into the eighth frame.
+	const frames = 7
+	start := bytes.Index(raw, []byte("data")) + 8
+	truncated := raw[:start+frames*4+1]
+	open := func() *Reader {
+		t.Helper()
+		r, err := NewReader(bytes.NewReader(truncated))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	n, err := open().ReadFloat32Into(make([]float32, 2*10))
+	if n != frames || err != io.ErrUnexpectedEOF {
+		t.Errorf("ReadFloat32Into = %d, %v, want %d, %v", n, err, frames, io.ErrUnexpectedEOF)
+	}
+	n, err = open().ReadBigEndian16(make([]byte, 4*10))
+	if n != frames*4 || err != io.ErrUnexpectedEOF {
+		t.Errorf("ReadBigEndian16 = %d, %v, want %d, %v", n, err, frames*4, io.ErrUnexpectedEOF)
+	}
+	var b bytes.Buffer
+	written, err := open().PipeFloat32LE(&b)
+	if written != frames*2*4 || err != io.ErrUnexpectedEOF {
+		t.Errorf("PipeFloat32LE = %d, %v, want %d, %v", written, err, frames*2*4, io.ErrUnexpectedEOF)
+	}
+
+	mono, err := open().Channel(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []int16
+	buf := makeSlices[int16](1, 3)
+	for {
+		n, err := mono.Read16PCM(buf)
+		got = append(got, buf[0][:n]...)
+		if err != nil {
+			if err != io.ErrUnexpectedEOF {
+				t.Errorf("Channel(1).Read16PCM: got error %v, want %v", err, io.ErrUnexpectedEOF)
+			}
+			break
+		}
+	}
+	if d := cmp.Diff(got, samples[1][:frames]); d != "" {
+		t.Errorf("Channel(1) mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestReadAny(t *testing.T) {
+	samples := [][]float64{{0, 0.5, -0.5, 0.25}}
+	for _, c := range []struct {
+		format   Format
+		bitDepth int
+		want     any
+	}{
+		{PCM, 8, [][]byte{{0x80, 0xc0, 0x40, 0xa0}}},
+		{PCM, 16, [][]int16{{0, 16384, -16384, 8192}}},
+		{PCM, 24, [][]int32{{0, 4194304, -4194304, 2097152}}},
+		{PCM, 32, [][]float64{{0, 0.5, -0.5, 0.25}}},
+		{ALaw, 8, [][]int16{{8, 8, -8, -8}}},
+		{IEEEFloat, 32, [][]float32{{0, 0.5, -0.5, 0.25}}},
+		{IEEEFloat, 64, [][]float64{{0, 0.5, -0.5, 0.25}}},
+	} {
+		t.Run(fmt.Sprintf("%v %d", c.format, c.bitDepth), func(t *testing.T) {
+			ff := FileFormat{
+				Format:     c.format,
+				BitDepth:   c.bitDepth,
+				Channels:   1,
+				SampleRate: 8000,
+			}
+			var raw []byte
+			switch {
+			case c.format == ALaw:
+				// Write doesn't encode A-law, so use the
+				// encodings of +8 and -8 directly.
+				raw = writeRaw(t, ff, []byte{0xd5, 0xd5, 0x55, 0x55})
+			case c.format == PCM && c.bitDepth == 32:
+				var data []byte
+				for _, s := range samples[0] {
+					data = binary.LittleEndian.AppendUint32(data, uint32(int32(s*math.MaxInt32)))
+				}
+				raw = writeRaw(t, ff, data)
+			default:
+				path := filepath.Join(t.TempDir(), "any.wav")
+				f, err := os.Create(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				w, err := NewWriter(f, ff)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, err := w.Write64Float(samples); err != nil {
+					t.Fatal(err)
+				}
+				if err := w.Close(); err != nil {
+					t.Fatal(err)
+				}
+				if err := f.Close(); err != nil {
+					t.Fatal(err)
+				}
+				if raw, err = os.ReadFile(path); err != nil {
+					t.Fatal(err)
+				}
+			}
+			r, err := NewReader(bytes.NewReader(raw))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := ReadAny(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d := cmp.Diff(got, c.want, cmpopts.EquateApprox(0, 1e-9)); d != "" {
+				t.Errorf("ReadAny() mismatch (-got, +want):\n%v", d)
+			}
+		})
+	}
+}
+
+func TestRoundTrip64Float(t *testing.T) {
+	// Values that don't survive being narrowed to 32 bits.
+	samples := [][]float64{
+		{math.Pi / 4, math.SmallestNonzeroFloat64, math.Copysign(0, -1)},
+		{math.Nextafter(0.5, 1), -1 + 1e-15, 1.0 / 3},
+	}
+	path := filepath.Join(t.TempDir(), "test.wav")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewWriter(f, FileFormat{
+		Format:     IEEEFloat,
+		BitDepth:   64,
+		Channels:   2,
+		SampleRate: 96000,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write64Float(samples); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadFull64Float(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bits := func(samples [][]float64) [][]uint64 {
+		out := makeSlices[uint64](len(samples), len(samples[0]))
+		for c := range samples {
+			for i, s := range samples[c] {
+				out[c][i] = math.Float64bits(s)
+			}
+		}
+		return out
+	}
+	if d := cmp.Diff(bits(got), bits(samples)); d != "" {
+		t.Errorf("64 bit float round trip mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func BenchmarkRead16PCMBuffers(b *testing.B) {
+	const frames = 512
+	b.Run("new slices", func(b *testing.B) {
+		r := silentReader()
+		b.ReportAllocs()
+		for b.Loop() {
+			if _, err := r.Read16PCM(makeSlices[int16](2, frames)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("FrameBuffer", func(b *testing.B) {
+		r := silentReader()
+		var fb FrameBuffer[int16]
+		b.ReportAllocs()
+		for b.Loop() {
+			if _, err := r.Read16PCM(fb.Reset(2, frames)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestFrameBuffer(t *testing.T) {
+	var fb FrameBuffer[int16]
+	frames := fb.Reset(2, 100)
+	if len(frames) != 2 || len(frames[0]) != 100 || len(frames[1]) != 100 {
+		t.Fatalf("Reset(2, 100) gave %d channels of %d samples", len(frames), len(frames[0]))
+	}
+	frames[1][0] = 7
+	// Appending to one channel mustn't overwrite the next.
+	_ = append(frames[0], 1)
+	if frames[1][0] != 7 {
+		t.Errorf("appending to channel 0 overwrote channel 1")
+	}
+	if allocs := testing.AllocsPerRun(10, func() {
+		fb.Reset(1, 150)
+		fb.Reset(2, 50)
+	}); allocs != 0 {
+		t.Errorf("Reset to smaller shapes made %v allocations, want 0", allocs)
+	}
+	if got := fb.Frames(); len(got) != 2 || len(got[1]) != 50 {
+		t.Errorf("Frames() after Reset(2, 50) has %d channels of %d samples", len(got), len(got[1]))
+	}
+}
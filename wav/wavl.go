@@ -0,0 +1,62 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// readWavl parses the body of a wave list chunk (a LIST chunk of type wavl,
+// without the type), which holds the audio as a sequence of data and slnt
+// chunks. It returns a reader for all of the audio, with the silent runs filled
+// in, and the total number of bytes it will return.
+func readWavl(list []byte, fc fmtChunk) (io.Reader, int64, error) {
+	// Silence is the middle of the range, which isn't 0 for 8 bit PCM.
+	var silent byte
+	if fc.sampleFormat() == PCM && fc.bitsPerSample <= 8 {
+		silent = 128
+	}
+	var (
+		readers []io.Reader
+		total   int64
+	)
+	err := walkSubchunks(list, func(id string, body []byte) error {
+		var n int64
+		switch id {
+		case "data":
+			readers = append(readers, bytes.NewReader(body))
+			n = int64(len(body))
+		case "slnt":
+			if len(body) < 4 {
+				return fmt.Errorf("slnt chunk too short: %d bytes", len(body))
+			}
+			// At most 2^32 frames of 2^16 bytes, so this can't
+			// overflow, but lots of them added up could.
+			n = int64(binary.LittleEndian.Uint32(body)) * int64(fc.blockAlign)
+			readers = append(readers, io.LimitReader(silence(silent), n))
+		default:
+			return fmt.Errorf("unexpected %q chunk", id)
+		}
+		if total > math.MaxInt64-n {
+			return fmt.Errorf("more than %d bytes of audio", int64(math.MaxInt64))
+		}
+		total += n
+		return nil
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("wavl: %w", err)
+	}
+	return io.MultiReader(readers...), total, nil
+}
+
+// silence is an io.Reader that endlessly returns the same byte.
+type silence byte
+
+func (s silence) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = byte(s)
+	}
+	return len(p), nil
+}
@@ -0,0 +1,81 @@
+package wav
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWavl(t *testing.T) {
+	list := cat(
+		[]byte("wavl"),
+		// Three samples of audio.
+		[]byte("data"), uint32le(6),
+		uint16le(1), uint16le(2), uint16le(0xFFFF),
+		// Then two samples of silence.
+		[]byte("slnt"), uint32le(4), uint32le(2),
+		// And one more sample.
+		[]byte("data"), uint32le(2), uint16le(4),
+	)
+	raw := writeRIFF(t, "WAVE",
+		rawChunk("fmt ", pcm16Fmt()),
+		rawChunk("LIST", list),
+	)
+
+	r, err := NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := r.Samples(); got != 6 {
+		t.Errorf("Samples() = %d, want 6", got)
+	}
+	got, err := ReadFull16PCM(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]int16{{1, 2, -1, 0, 0, 4}}
+	if d := cmp.Diff(got, want); d != "" {
+		t.Errorf("samples mismatch (-got, +want):\n%v", d)
+	}
+}
+
+func TestWavlHuge(t *testing.T) {
+	// The longest run of silence there can be, which is more bytes than
+	// fit in an int on 32 bit platforms.
+	raw := writeRIFF(t, "WAVE",
+		rawChunk("fmt ", pcm16Fmt()),
+		rawChunk("LIST", cat(
+			[]byte("wavl"),
+			[]byte("slnt"), uint32le(4), uint32le(math.MaxUint32),
+		)),
+	)
+	r, err := NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := r.Samples(), int(min(math.MaxUint32, math.MaxInt)); got != want {
+		t.Errorf("Samples() = %d, want %d", got, want)
+	}
+	got := makeSlices[int16](1, 10)
+	if _, err := r.Read16PCM(got); err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff(got, makeSlices[int16](1, 10)); d != "" {
+		t.Errorf("samples mismatch (-got, +want):\n%v", d)
+	}
+
+	// A data chunk claiming to be bigger than the list is corrupt, and
+	// shouldn't be trusted on 32 bit platforms either.
+	raw = writeRIFF(t, "WAVE",
+		rawChunk("fmt ", pcm16Fmt()),
+		rawChunk("LIST", cat(
+			[]byte("wavl"),
+			[]byte("data"), uint32le(0x80000000), uint16le(1),
+		)),
+	)
+	if _, err := NewReader(bytes.NewReader(raw)); err == nil {
+		t.Error("NewReader with an oversized wavl data chunk: expected error")
+	}
+}
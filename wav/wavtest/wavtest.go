@@ -0,0 +1,140 @@
+// package wavtest provides helpers for testing code that reads and writes wav
+// files.
+package wavtest
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pfcm/audiofile/wav"
+)
+
+// format is everything we compare about the formats of two files.
+type format struct {
+	Format     wav.Format
+	BitDepth   int
+	Channels   int
+	SampleRate int
+	Samples    int
+}
+
+func formatOf(r *wav.Reader) format {
+	return format{
+		Format:     r.Format(),
+		BitDepth:   r.BitDepth(),
+		Channels:   r.Channels(),
+		SampleRate: r.Samplerate(),
+		Samples:    r.Samples(),
+	}
+}
+
+// AssertRoundTrip reads the wav file in raw, writes it back out in the same
+// format, copying its other chunks with wav.CopyMetadata, and checks that
+// nothing changed. It reports these separately:
+//
+//   - the RIFF ID and form type, but not the size,
+//   - the format, including the number of samples,
+//   - the raw bytes of the audio data, and
+//   - every other chunk apart from fmt and data, by ID and contents, in order.
+//
+// The files aren't compared byte for byte, because the Writer puts copied
+// chunks after the audio data wherever they were in raw.
+func AssertRoundTrip(t testing.TB, raw []byte) {
+	t.Helper()
+	r, err := wav.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("Opening original file: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "roundtrip.wav")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := r.EquivalentWriter(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		t.Fatal(err)
+	}
+	if err := wav.CopyMetadata(w, r); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The header is the 4 byte RIFF ID, the 4 byte size and the 4 byte
+	// form type. The size depends on the layout, so it is skipped.
+	if d := cmp.Diff(header(got), header(raw)); d != "" {
+		t.Errorf("RIFF header mismatch (-got, +want):\n%v", d)
+	}
+
+	gr, err := wav.NewReader(bytes.NewReader(got))
+	if err != nil {
+		t.Fatalf("Opening file we wrote: %v", err)
+	}
+	wr, err := wav.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("Opening original file: %v", err)
+	}
+	if d := cmp.Diff(formatOf(gr), formatOf(wr)); d != "" {
+		t.Errorf("Format difference (-got, +want):\n%v", d)
+	}
+
+	gotData, err := io.ReadAll(gr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantData, err := io.ReadAll(wr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff(gotData, wantData); d != "" {
+		t.Errorf("Data difference (-got, +want):\n%v", d)
+	}
+
+	gotChunks, err := chunksOf(gr)
+	if err != nil {
+		t.Fatalf("Reading chunks of file we wrote: %v", err)
+	}
+	wantChunks, err := chunksOf(wr)
+	if err != nil {
+		t.Fatalf("Reading chunks of original file: %v", err)
+	}
+	if d := cmp.Diff(gotChunks, wantChunks); d != "" {
+		t.Errorf("Chunk difference (-got, +want):\n%v", d)
+	}
+}
+
+// header returns the RIFF ID and form type from the start of a file.
+func header(raw []byte) []byte {
+	if len(raw) < 12 {
+		return raw
+	}
+	return append(raw[:4:4], raw[8:12]...)
+}
+
+// chunksOf returns all of the chunks in r other than fmt and data.
+func chunksOf(r *wav.Reader) ([]wav.MetadataChunk, error) {
+	var chunks []wav.MetadataChunk
+	for mc, err := range r.AllMetadata() {
+		if err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, mc)
+	}
+	return chunks, nil
+}
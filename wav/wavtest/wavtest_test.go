@@ -0,0 +1,52 @@
+package wavtest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pfcm/audiofile/wav"
+)
+
+func TestAssertRoundTrip(t *testing.T) {
+	raw, err := os.ReadFile("../../testdata/kick.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertRoundTrip(t, raw)
+}
+
+func TestAssertRoundTripMetadata(t *testing.T) {
+	// Cue points add a cue chunk and a LIST chunk after the audio.
+	path := filepath.Join(t.TempDir(), "cues.wav")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := wav.NewWriter(f, wav.FileFormat{
+		Format:     wav.PCM,
+		BitDepth:   16,
+		Channels:   1,
+		SampleRate: 44100,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.AppendTake([][]int16{{1, 2, 3}}, "one"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.AppendTake([][]int16{{4, 5}}, "two"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertRoundTrip(t, raw)
+}
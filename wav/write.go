@@ -1,10 +1,15 @@
 package wav
 
 import (
+	"bytes"
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"hash"
+	"hash/crc32"
 	"io"
+	"math"
+	"slices"
 
 	"github.com/pfcm/audiofile/riff"
 )
@@ -20,66 +25,317 @@ type FileFormat struct {
 	Channels int
 	// SampleRate is the number of samples to play per second.
 	SampleRate int
+	// ForceExtensible makes the fmt chunk use the Extensible format, with
+	// Format as the subformat. Extensible is always used when the spec
+	// requires it, for more than two channels or PCM with more than 16 bits
+	// per sample.
+	ForceExtensible bool
+	// ValidBits is how many of the BitDepth bits of each sample hold audio,
+	// such as 24 bit audio in 32 bit containers, if it isn't all of them.
+	// Setting it makes the fmt chunk Extensible. 0 means all of them.
+	ValidBits int
+	// ChannelMask says which speakers the channels are for, see
+	// Reader.ChannelMask. Setting it makes the fmt chunk Extensible. If it
+	// is 0, Extensible files get the usual mask for the number of
+	// channels.
+	ChannelMask uint32
 }
 
 func (ff FileFormat) chunk() (fmtChunk, error) {
-	bytesPerSample := max(8, ff.BitDepth) / 8
-	return fmtChunk{
-		format:     ff.Format,
-		channels:   uint16(ff.Channels),
-		sampleRate: uint32(ff.SampleRate),
-		// TODO: not correct for weird bit depths
-		dataRate:      uint32(bytesPerSample * ff.Channels * ff.SampleRate),
-		blockAlign:    uint16(bytesPerSample * ff.Channels),
+	switch bd := ff.BitDepth; ff.Format {
+	case PCM:
+		if bd < 1 || bd > 32 {
+			return fmtChunk{}, fmt.Errorf("unsupported bit depth %d for %v", bd, ff.Format)
+		}
+	case IEEEFloat:
+		if bd != 32 && bd != 64 {
+			return fmtChunk{}, fmt.Errorf("unsupported bit depth %d for %v", bd, ff.Format)
+		}
+	case ALaw, MuLaw:
+		if bd != 8 {
+			return fmtChunk{}, fmt.Errorf("unsupported bit depth %d for %v", bd, ff.Format)
+		}
+	default:
+		return fmtChunk{}, fmt.Errorf("writing %v not supported", ff.Format)
+	}
+	if ff.ValidBits < 0 || ff.ValidBits > ff.BitDepth {
+		return fmtChunk{}, fmt.Errorf("%d valid bits out of range for %d bit samples", ff.ValidBits, ff.BitDepth)
+	}
+	// Samples that aren't a whole number of bytes are padded out to the
+	// next byte.
+	bytesPerSample := (ff.BitDepth + 7) / 8
+	blockAlign := bytesPerSample * ff.Channels
+	fc := fmtChunk{
+		format:        ff.Format,
+		channels:      uint16(ff.Channels),
+		sampleRate:    uint32(ff.SampleRate),
+		dataRate:      uint32(blockAlign * ff.SampleRate),
+		blockAlign:    uint16(blockAlign),
 		bitsPerSample: uint16(ff.BitDepth),
-	}, nil
+	}
+	partial := ff.ValidBits != 0 && ff.ValidBits != ff.BitDepth
+	if ff.ForceExtensible || partial || ff.ChannelMask != 0 || ff.Channels > 2 || (ff.Format == PCM && ff.BitDepth > 16) {
+		// Extensible files always have whole bytes per sample, and say
+		// how many of the bits are used separately.
+		fc.format = Extensible
+		fc.subFormat = ff.Format
+		fc.bitsPerSample = uint16(bytesPerSample * 8)
+		fc.validBitsPerSample = uint16(ff.BitDepth)
+		if ff.ValidBits != 0 {
+			fc.validBitsPerSample = uint16(ff.ValidBits)
+		}
+		fc.channelMask = defaultChannelMask(ff.Channels)
+		if ff.ChannelMask != 0 {
+			fc.channelMask = ff.ChannelMask
+		}
+	}
+	return fc, nil
+}
+
+// ErrDataChunkOverflow is returned by the Writer if writing more data would make
+// the file larger than a RIFF file can describe. Close also returns it if the
+// chunks that go after the data, such as metadata and cue points, don't fit.
+var ErrDataChunkOverflow = errors.New("wav: data chunk overflow")
+
+// MaxSamples returns the largest number of samples per channel that can be
+// written to a file with this format. It doesn't account for any metadata
+// chunks.
+func (ff FileFormat) MaxSamples() int {
+	fc, err := ff.chunk()
+	if err != nil || fc.blockAlign == 0 {
+		return 0
+	}
+	// On 32 bit platforms, the limit for small frames doesn't fit in an
+	// int.
+	return int(min(maxDataBytes(fc)/int64(fc.blockAlign), math.MaxInt))
+}
+
+// maxDataBytes returns the largest number of bytes that can go into the data
+// chunk of a file, given that the size of the whole RIFF chunk has to fit in a
+// uint32.
+func maxDataBytes(fc fmtChunk) int64 {
+	// The RIFF chunk's size includes the form type, and the headers and
+	// data for all of the other chunks.
+	overhead := int64(4 + 8 + fmtChunkSize(fc) + 8)
+	if needsFact(fc) {
+		overhead += 8 + 4
+	}
+	return math.MaxUint32 - overhead
 }
 
 // Writer writes wav files.
 type Writer struct {
 	fmt fmtChunk
+	ws  io.WriteSeeker
 	w   *riff.Writer
-	// dc is the data chunk, where the samples are actually written.
+	// dc is the data chunk, where the samples are actually written. It is
+	// only started when the first samples are written, so that other
+	// chunks can go before it.
 	dc io.WriteCloser
+	// closed is true once Close has been called.
+	closed bool
+	// extraBytes is the number of bytes used by chunks written before the
+	// data chunk, other than fmt and fact.
+	extraBytes int
+	// dataBytes is the number of bytes written to the data chunk so far.
+	// It can be more than fits in an int on 32 bit platforms.
+	dataBytes int64
+	// factOffset is the offset in ws of the sample count in the fact
+	// chunk, or 0 if there is no fact chunk.
+	factOffset int64
+	// deferredOffset is the offset in ws of the space reserved for the fmt
+	// chunk, or 0 if it has already been written. See WithDeferredFormat.
+	deferredOffset int64
+	// metadata holds extra chunks to write after the data chunk.
+	metadata []metadataChunk
+	// promote holds all of the samples if the format might be changed
+	// when the Writer is closed. See WithPromoteOnClip.
+	promote *promoter
+	// fade applies any fades as data is written. See WithFadeIn and
+	// WithFadeOut.
+	fade *fader
+	// sum is the checksum of the data chunk so far, or nil if it isn't
+	// needed. See WithIntegrityChunk.
+	sum hash.Hash32
+	// cues are markers to write in a cue chunk on Close. See AppendTake.
+	cues []cuePoint
+	// peak tracks the peak of each channel, or is nil if there is no
+	// PEAK chunk. See WithPeakChunk.
+	peak *peakTracker
+	// readback reads the file back on Close, or is nil if it shouldn't be
+	// checked. See WithReadback.
+	readback io.ReaderAt
+	// stream gets the whole file on Close, if ws is just a buffer. See
+	// NewStreamWriter.
+	stream io.Writer
 
 	scratch []byte
 }
 
+// WriterOption configures optional behaviour of a Writer.
+type WriterOption func(*writerOptions)
+
+type writerOptions struct {
+	promoteOnClip   bool
+	rf64Reservation bool
+	fadeIn, fadeOut int
+	integrity       bool
+	peak            bool
+	deferredFormat  bool
+	readback        io.ReaderAt
+}
+
+// WithRF64Reservation makes the Writer reserve space at the start of the file
+// for the ds64 chunk of an RF64 file, using a JUNK chunk. This means the file
+// can be converted to RF64 later without moving everything.
+func WithRF64Reservation() WriterOption {
+	return func(o *writerOptions) {
+		o.rf64Reservation = true
+	}
+}
+
+// ds64Size is the size of the body of a ds64 chunk with no table entries: the
+// 64 bit sizes of the RIFF and data chunks, the 64 bit sample count and the 32
+// bit table length.
+const ds64Size = 8 + 8 + 8 + 4
+
 // NewWriter initialises a wav writer.
-func NewWriter(ws io.WriteSeeker, ff FileFormat) (*Writer, error) {
+func NewWriter(ws io.WriteSeeker, ff FileFormat, opts ...WriterOption) (*Writer, error) {
 	fc, err := ff.chunk()
 	if err != nil {
 		return nil, err
 	}
-	return newWriter(ws, fc)
+	var o writerOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if (o.fadeIn > 0 || o.fadeOut > 0) && !canScale(fc) {
+		return nil, fmt.Errorf("can't fade %d bit %v", fc.bitsPerSample, ff.Format)
+	}
+	if o.deferredFormat && o.promoteOnClip {
+		return nil, errors.New("can't defer the format and promote on clip")
+	}
+	var w *Writer
+	if o.promoteOnClip && fc.sampleFormat() == PCM {
+		w, err = newPromotingWriter(ws, fc, o)
+	} else {
+		w, err = newWriter(ws, fc, o)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if o.fadeIn > 0 || o.fadeOut > 0 {
+		w.fade = &fader{in: o.fadeIn, out: o.fadeOut}
+	}
+	if o.integrity {
+		w.sum = crc32.NewIEEE()
+	}
+	if o.peak {
+		w.peak = newPeakTracker(ff.Channels)
+	}
+	w.readback = o.readback
+	return w, nil
 }
 
-func newWriter(ws io.WriteSeeker, fc fmtChunk) (*Writer, error) {
+// startRIFF starts writing a WAVE file, reserving space for RF64 if needed. It
+// returns the riff.Writer and the number of bytes used by any reservation.
+func startRIFF(ws io.WriteSeeker, o writerOptions) (*riff.Writer, int, error) {
 	rw, err := riff.NewWriter(ws, "WAVE")
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
-	wc, err := rw.NewChunk("fmt ")
+	if !o.rf64Reservation {
+		return rw, 0, nil
+	}
+	// The JUNK chunk has to come first, so it's in the right place to
+	// become a ds64 chunk.
+	if err := rw.WriteChunk(metadataChunk{id: "JUNK", data: make([]byte, ds64Size)}.riffChunk()); err != nil {
+		return nil, 0, err
+	}
+	return rw, 8 + ds64Size, nil
+}
+
+func newWriter(ws io.WriteSeeker, fc fmtChunk, o writerOptions) (*Writer, error) {
+	rw, reserved, err := startRIFF(ws, o)
 	if err != nil {
 		return nil, err
 	}
-	if err := writeFmtChunk(wc, fc); err != nil {
-		return nil, err
+	if o.deferredFormat {
+		offset, err := reserveFmt(ws, rw)
+		if err != nil {
+			return nil, err
+		}
+		return &Writer{
+			fmt: fc,
+			ws:  ws,
+			w:   rw,
+			// maxDataBytes already allows for some of the
+			// reservation, but it's simpler to count it all.
+			extraBytes:     reserved + deferredBytes,
+			deferredOffset: offset,
+		}, nil
 	}
-	if err := wc.Close(); err != nil {
+	if err := writeFmt(rw, fc); err != nil {
 		return nil, err
 	}
-	dc, err := rw.NewChunk("data")
-	if err != nil {
-		return nil, err
+	var factOffset int64
+	if needsFact(fc) {
+		// The fact chunk holds the number of samples per channel, which
+		// we don't know yet. Write a placeholder and remember where it
+		// is so Close can fill it in.
+		// TODO: make riff.Writer able to do this.
+		pos, err := ws.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, err
+		}
+		// Skip the chunk header.
+		factOffset = pos + 8
+		if err := rw.WriteChunk(&riff.Chunk{
+			Identifier: "fact",
+			Size:       4,
+			Reader:     bytes.NewReader(make([]byte, 4)),
+		}); err != nil {
+			return nil, err
+		}
 	}
 	return &Writer{
-		fmt: fc,
-		w:   rw,
-		dc:  dc,
+		fmt:        fc,
+		ws:         ws,
+		w:          rw,
+		extraBytes: reserved,
+		factOffset: factOffset,
 	}, nil
 }
 
+// writeFmt writes fc as a fmt chunk.
+func writeFmt(rw *riff.Writer, fc fmtChunk) error {
+	wc, err := rw.NewChunk("fmt ")
+	if err != nil {
+		return err
+	}
+	if err := writeFmtChunk(wc, fc); err != nil {
+		return err
+	}
+	return wc.Close()
+}
+
+// needsFact reports whether a file with the provided format should have a fact
+// chunk. The spec requires one for every format other than PCM.
+func needsFact(fc fmtChunk) bool {
+	return fc.sampleFormat() != PCM
+}
+
+// fmtChunkSize returns the number of bytes writeFmtChunk will write.
+func fmtChunkSize(fc fmtChunk) int {
+	switch fc.format {
+	case IEEEFloat, ALaw, MuLaw:
+		return 18
+	case Extensible:
+		return 40
+	}
+	return 16
+}
+
 func writeFmtChunk(w io.Writer, fc fmtChunk) error {
 	scratch := make([]byte, 0, 16)
 
@@ -99,7 +355,7 @@ func writeFmtChunk(w io.Writer, fc fmtChunk) error {
 	switch fc.format {
 	case PCM:
 		// it is done.
-	case ALaw, MuLaw:
+	case IEEEFloat, ALaw, MuLaw:
 		put16(0)
 	case Extensible:
 		put16(22)
@@ -114,10 +370,7 @@ func writeFmtChunk(w io.Writer, fc fmtChunk) error {
 }
 
 func (w *Writer) format() Format {
-	if w.fmt.format == Extensible {
-		return w.fmt.subFormat
-	}
-	return w.fmt.format
+	return w.fmt.sampleFormat()
 }
 
 // Write implements io.Writer, writing raw bytes to the data chunk. These should
@@ -125,10 +378,35 @@ func (w *Writer) format() Format {
 // in interleaved. Usually it will be easier to use one of the other write
 // methods.
 func (w *Writer) Write(p []byte) (int, error) {
-	if w.dc == nil {
+	if w.closed {
 		return 0, errors.New("Write called after Close")
 	}
-	return w.dc.Write(p)
+	if w.promote != nil {
+		return 0, errors.New("Write can't be used with WithPromoteOnClip")
+	}
+	if w.dataBytes+int64(w.fade.pending())+int64(len(p)) > maxDataBytes(w.fmt)-int64(w.extraBytes) {
+		return 0, ErrDataChunkOverflow
+	}
+	if w.fade != nil {
+		return w.writeFaded(p)
+	}
+	return w.writeData(p)
+}
+
+// writeData writes p straight to the data chunk.
+func (w *Writer) writeData(p []byte) (int, error) {
+	if err := w.startData(); err != nil {
+		return 0, err
+	}
+	n, err := w.dc.Write(p)
+	w.dataBytes += int64(n)
+	if w.sum != nil {
+		w.sum.Write(p[:n])
+	}
+	if err == nil {
+		err = w.trackPeaks(p[:n])
+	}
+	return n, err
 }
 
 // Write8PCM writes the provided 8 bit PCM samples to the file, converting to
@@ -137,9 +415,12 @@ func (w *Writer) Write(p []byte) (int, error) {
 // have the same number of samples. Returns the number of bytes eventually
 // written to the file.
 func (w *Writer) Write8PCM(samples [][]byte) (int, error) {
-	if err := w.checkChannels(len(samples)); err != nil {
+	if err := checkSamples(w, samples); err != nil {
 		return 0, err
 	}
+	if w.promote != nil {
+		return promoteSamples(w, samples, from8PCMToFloat32, false)
+	}
 	var appendSample func([]byte, byte) []byte
 	switch f := w.format(); f {
 	case PCM:
@@ -162,8 +443,7 @@ func (w *Writer) Write8PCM(samples [][]byte) (int, error) {
 	default:
 		return 0, fmt.Errorf("writing 8 bit PCM -> %v not implemented", f)
 	}
-	// TODO: actually reuse a scratch buffer.
-	return writeSamples(w, w.scratch, samples, appendSample)
+	return writeSamples(w, samples, appendSample)
 }
 
 // Write16PCM writes the provided 16 bit PCM samples to the file, converting to
@@ -172,14 +452,20 @@ func (w *Writer) Write8PCM(samples [][]byte) (int, error) {
 // have the same number of samples. Returns the number of bytes eventually
 // written to the file.
 func (w *Writer) Write16PCM(samples [][]int16) (int, error) {
-	if err := w.checkChannels(len(samples)); err != nil {
+	if err := checkSamples(w, samples); err != nil {
 		return 0, err
 	}
+	if w.promote != nil {
+		return promoteSamples(w, samples, from16PCMToFloat32, false)
+	}
 	var appendSample func([]byte, int16) []byte
 	switch f := w.format(); f {
 	case PCM:
 		switch bd := w.fmt.bitsPerSample; {
 		case bd <= 8:
+			appendSample = func(bs []byte, i int16) []byte {
+				return append(bs, from16PCMTo8PCM(i))
+			}
 		case bd <= 16:
 			appendSample = func(bs []byte, i int16) []byte {
 				return binary.LittleEndian.AppendUint16(bs, uint16(i))
@@ -190,39 +476,318 @@ func (w *Writer) Write16PCM(samples [][]int16) (int, error) {
 	default:
 		return 0, fmt.Errorf("writing 16 bit PCM -> %v not implemented", f)
 	}
-	return writeSamples(w, w.scratch, samples, appendSample)
+	return writeSamples(w, samples, appendSample)
 }
 
-func (w *Writer) checkChannels(channels int) error {
-	if channels == int(w.fmt.channels) {
-		return nil
+// Write24PCM writes the provided 24 bit PCM samples to the file, converting to
+// the file's format if necessary. Each sample is held in the low 24 bits of an
+// int32, values outside of the 24 bit range are clipped. The first index of the
+// provided samples should have a slice per channel (the first index) and each
+// channel should have the same number of samples. Returns the number of bytes
+// eventually written to the file.
+func (w *Writer) Write24PCM(samples [][]int32) (int, error) {
+	if err := checkSamples(w, samples); err != nil {
+		return 0, err
+	}
+	if w.promote != nil {
+		return promoteSamples(w, samples, func(i int32) float32 {
+			return from24PCMToFloat32(clampInt24(i))
+		}, false)
+	}
+	var appendSample func([]byte, int32) []byte
+	switch f := w.format(); f {
+	case PCM:
+		switch bd := w.fmt.bitsPerSample; {
+		case bd <= 8:
+			appendSample = func(bs []byte, i int32) []byte {
+				return append(bs, from24PCMTo8PCM(clampInt24(i)))
+			}
+		case bd <= 16:
+			appendSample = func(bs []byte, i int32) []byte {
+				return binary.LittleEndian.AppendUint16(bs, uint16(from24PCMTo16PCM(clampInt24(i))))
+			}
+		case bd <= 24:
+			appendSample = func(bs []byte, i int32) []byte {
+				return appendInt24(bs, clampInt24(i))
+			}
+		default:
+			return 0, fmt.Errorf("writing 24 bit PCM -> %v bit PCM not implemented", bd)
+		}
+	case IEEEFloat:
+		switch bd := w.fmt.bitsPerSample; bd {
+		case 32:
+			appendSample = func(bs []byte, i int32) []byte {
+				return binary.LittleEndian.AppendUint32(bs, math.Float32bits(from24PCMToFloat32(clampInt24(i))))
+			}
+		case 64:
+			appendSample = func(bs []byte, i int32) []byte {
+				return binary.LittleEndian.AppendUint64(bs, math.Float64bits(from24PCMToFloat64(clampInt24(i))))
+			}
+		default:
+			return 0, fmt.Errorf("writing 24 bit PCM -> %v bit float not implemented", bd)
+		}
+	default:
+		return 0, fmt.Errorf("writing 24 bit PCM -> %v not implemented", f)
+	}
+	return writeSamples(w, samples, appendSample)
+}
+
+// clampInt24 limits i to the range of a 24 bit sample.
+func clampInt24(i int32) int32 {
+	return max(-1<<23, min(i, 1<<23-1))
+}
+
+// Write32Float writes the provided 32 bit float samples to the file, converting
+// to the file's format if necessary. The first index of the provided samples
+// should have a slice per channel (the first index) and each channel should
+// have the same number of samples. Returns the number of bytes eventually
+// written to the file.
+func (w *Writer) Write32Float(samples [][]float32) (int, error) {
+	if err := checkSamples(w, samples); err != nil {
+		return 0, err
+	}
+	if w.promote != nil {
+		return promoteSamples(w, samples, func(f float32) float32 { return f }, true)
+	}
+	appendSample, err := float32Encoder(w.fmt)
+	if err != nil {
+		return 0, err
+	}
+	return writeSamples(w, samples, appendSample)
+}
+
+// float32Encoder returns a function that appends a 32 bit float sample to a
+// byte slice, converted to the format described by fc.
+func float32Encoder(fc fmtChunk) (func([]byte, float32) []byte, error) {
+	switch f := fc.sampleFormat(); f {
+	case PCM:
+		switch bd := fc.bitsPerSample; {
+		case bd <= 8:
+			return func(bs []byte, f float32) []byte {
+				return append(bs, fromFloat32To8PCM(f))
+			}, nil
+		case bd <= 16:
+			return func(bs []byte, f float32) []byte {
+				return binary.LittleEndian.AppendUint16(bs, uint16(fromFloat32To16PCM(f)))
+			}, nil
+		case bd <= 24:
+			return func(bs []byte, f float32) []byte {
+				return appendInt24(bs, fromFloat32To24PCM(f))
+			}, nil
+		default:
+			return nil, fmt.Errorf("writing 32 bit float -> %v bit PCM not implemented", bd)
+		}
+	case IEEEFloat:
+		switch bd := fc.bitsPerSample; bd {
+		case 32:
+			return func(bs []byte, f float32) []byte {
+				return binary.LittleEndian.AppendUint32(bs, math.Float32bits(f))
+			}, nil
+		case 64:
+			return func(bs []byte, f float32) []byte {
+				return binary.LittleEndian.AppendUint64(bs, math.Float64bits(float64(f)))
+			}, nil
+		default:
+			return nil, fmt.Errorf("writing 32 bit float -> %v bit float not implemented", bd)
+		}
+	default:
+		return nil, fmt.Errorf("writing 32 bit float -> %v not implemented", f)
+	}
+}
+
+// Write64Float writes the provided 64 bit float samples to the file, converting
+// to the file's format if necessary. The first index of the provided samples
+// should have a slice per channel (the first index) and each channel should
+// have the same number of samples. Returns the number of bytes eventually
+// written to the file.
+func (w *Writer) Write64Float(samples [][]float64) (int, error) {
+	if err := checkSamples(w, samples); err != nil {
+		return 0, err
+	}
+	if w.promote != nil {
+		return promoteSamples(w, samples, fromFloat64ToFloat32, true)
+	}
+	var appendSample func([]byte, float64) []byte
+	switch f := w.format(); f {
+	case PCM:
+		switch bd := w.fmt.bitsPerSample; {
+		case bd <= 8:
+			appendSample = func(bs []byte, f float64) []byte {
+				return append(bs, fromFloat64To8PCM(f))
+			}
+		case bd <= 16:
+			appendSample = func(bs []byte, f float64) []byte {
+				return binary.LittleEndian.AppendUint16(bs, uint16(fromFloat64To16PCM(f)))
+			}
+		case bd <= 24:
+			appendSample = func(bs []byte, f float64) []byte {
+				return appendInt24(bs, fromFloat64To24PCM(f))
+			}
+		default:
+			return 0, fmt.Errorf("writing 64 bit float -> %v bit PCM not implemented", bd)
+		}
+	case IEEEFloat:
+		switch bd := w.fmt.bitsPerSample; bd {
+		case 32:
+			appendSample = func(bs []byte, f float64) []byte {
+				return binary.LittleEndian.AppendUint32(bs, math.Float32bits(float32(f)))
+			}
+		case 64:
+			appendSample = func(bs []byte, f float64) []byte {
+				return binary.LittleEndian.AppendUint64(bs, math.Float64bits(f))
+			}
+		default:
+			return 0, fmt.Errorf("writing 64 bit float -> %v bit float not implemented", bd)
+		}
+	default:
+		return 0, fmt.Errorf("writing 64 bit float -> %v not implemented", f)
+	}
+	return writeSamples(w, samples, appendSample)
+}
+
+// appendInt24 appends the low 3 bytes of i, little-endian.
+func appendInt24(bs []byte, i int32) []byte {
+	return append(bs, byte(i), byte(i>>8), byte(i>>16))
+}
+
+// checkSamples makes sure samples has a slice for each channel in the file, and
+// that they all have the same, non-zero, length.
+func checkSamples[T any](w *Writer, samples [][]T) error {
+	if channels := len(samples); channels != int(w.fmt.channels) {
+		return fmt.Errorf("wrong number of channels %d: expect %d", channels, w.fmt.channels)
+	}
+	for c := range samples {
+		if len(samples[c]) == 0 {
+			return fmt.Errorf("channel %d has no samples", c)
+		}
+		if len(samples[c]) != len(samples[0]) {
+			return fmt.Errorf("channel %d has %d samples, channel 0 has %d", c, len(samples[c]), len(samples[0]))
+		}
 	}
-	return fmt.Errorf("wrong number of channels %d: expect %d", channels, w.fmt.channels)
+	return nil
 }
 
+// writeBatchSize is roughly how many bytes writeSamples interleaves before
+// writing them out.
+const writeBatchSize = 64 * 1024
+
+// writeSamples interleaves and encodes samples, writing them to w in batches
+// so the amount of memory used doesn't depend on how many samples there are.
 func writeSamples[T any](
-	w io.Writer,
-	scratch []byte,
+	w *Writer,
 	samples [][]T,
 	appendSample func([]byte, T) []byte,
 ) (int, error) {
-	scratch = scratch[:0]
+	if cap(w.scratch) < writeBatchSize {
+		// Leave a little room so the last frame of a batch doesn't
+		// need to grow it.
+		w.scratch = make([]byte, 0, writeBatchSize+int(w.fmt.blockAlign))
+	}
+	var (
+		scratch = w.scratch[:0]
+		written int
+	)
 	for i := range samples[0] {
 		for c := range samples {
 			scratch = appendSample(scratch, samples[c][i])
 		}
+		if len(scratch) >= writeBatchSize {
+			n, err := w.Write(scratch)
+			written += n
+			if err != nil {
+				return written, err
+			}
+			scratch = scratch[:0]
+		}
+	}
+	n, err := w.Write(scratch)
+	return written + n, err
+}
+
+// startData starts the data chunk, if it hasn't been already.
+func (w *Writer) startData() error {
+	if w.dc != nil {
+		return nil
+	}
+	dc, err := w.w.NewChunk("data")
+	if err != nil {
+		return err
+	}
+	w.dc = dc
+	return nil
+}
+
+// WriteID3 writes an id3 chunk containing tag, which should be an already
+// encoded ID3v2 tag. It has to be called before any audio is written.
+func (w *Writer) WriteID3(tag []byte) error {
+	if w.closed || w.dc != nil {
+		return errors.New("WriteID3 called after writing audio")
+	}
+	if err := w.w.WriteChunk(metadataChunk{id: "id3 ", data: tag}.riffChunk()); err != nil {
+		return err
 	}
-	return w.Write(scratch)
+	// The header, the tag and possibly a pad byte.
+	w.extraBytes += 8 + len(tag) + len(tag)%2
+	return nil
 }
 
 // Close finalises the file.
 func (w *Writer) Close() error {
-	if err := w.dc.Close(); err != nil {
-		return err
+	if w.closed {
+		return errors.New("Close called twice")
 	}
+	// Even if the held back samples can't be written, or the data chunk
+	// can't be finished, make sure the RIFF chunk is, and report all of
+	// the errors.
+	flushErr := errors.Join(w.flushPromoted(), w.flushFade())
+	w.closed = true
+	// Make sure there's a data chunk, even if it's empty.
+	if err := w.startData(); err != nil {
+		return errors.Join(flushErr, err, w.w.Close())
+	}
+	err := w.dc.Close()
 	w.dc = nil
-	if err := w.w.Close(); err != nil {
+	if err == nil {
+		err = w.finishChunks()
+	}
+	if err := errors.Join(flushErr, err, w.w.Close()); err != nil {
+		return err
+	}
+	if err := w.verifyReadback(); err != nil {
+		return err
+	}
+	return w.flushStream()
+}
+
+// finishChunks writes everything that goes after the data chunk, and fills in
+// the fmt chunk if it was deferred and the fact chunk.
+func (w *Writer) finishChunks() error {
+	if err := w.writeDeferredFormat(); err != nil {
 		return err
 	}
+	trailing := slices.Concat(w.metadata, w.cueChunks(), w.peakChunks(), w.integrityChunks())
+	// Write only checks that the data fits, so make sure the chunks after
+	// it do too.
+	var size int64
+	for _, mc := range trailing {
+		size += int64(8 + len(mc.data) + len(mc.data)%2)
+	}
+	if w.dataBytes+w.dataBytes%2+size > maxDataBytes(w.fmt)-int64(w.extraBytes) {
+		return ErrDataChunkOverflow
+	}
+	for _, mc := range trailing {
+		if err := w.w.WriteChunk(mc.riffChunk()); err != nil {
+			return err
+		}
+	}
+	if w.factOffset != 0 {
+		if _, err := w.ws.Seek(w.factOffset, io.SeekStart); err != nil {
+			return err
+		}
+		samples := w.dataBytes / int64(w.fmt.blockAlign)
+		if err := binary.Write(w.ws, binary.LittleEndian, uint32(samples)); err != nil {
+			return err
+		}
+	}
 	return nil
 }